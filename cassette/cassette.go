@@ -0,0 +1,93 @@
+package cassette
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Request запрос в кассете. Заголовки (в том числе Authorization) не сохраняются
+type Request struct {
+	Method string     `json:"method"`
+	Path   string     `json:"path"`
+	Query  url.Values `json:"query,omitempty"`
+	Form   url.Values `json:"form,omitempty"`
+}
+
+// Response ответ в кассете
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Interaction пара запрос/ответ, одна строка JSONL-файла
+type Interaction struct {
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Key ключ сопоставления: метод, путь и отсортированные параметры формы и строки запроса
+func (r Request) Key() string {
+	return r.Method + " " + r.Path + "?" + r.Query.Encode() + "#" + r.Form.Encode()
+}
+
+// NewRequest извлекает из HTTP-запроса то, что сохраняется в кассете; тело возвращается для повторной отправки
+func NewRequest(req *http.Request) (Request, []byte, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return Request{}, nil, err
+	}
+
+	recorded := Request{Method: req.Method, Path: req.URL.Path}
+	if query := req.URL.Query(); len(query) > 0 {
+		recorded.Query = query
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return Request{}, nil, fmt.Errorf("parse form: %w", err)
+		}
+		if len(form) > 0 {
+			recorded.Form = form
+		}
+	}
+	return recorded, body, nil
+}
+
+// Load читает все взаимодействия из JSONL-файла
+func Load(path string) ([]Interaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var interaction Interaction
+		if err := json.Unmarshal([]byte(text), &interaction); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return interactions, nil
+}
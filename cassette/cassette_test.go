@@ -0,0 +1,174 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newUpstream заменяет настоящий Robot: отвечает методом, путём, формой и номером обращения к пути
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	calls := map[string]int{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, _, ok := r.BasicAuth(); !ok || username != "robot" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		mu.Lock()
+		calls[r.URL.Path]++
+		call := calls[r.URL.Path]
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, r.Method+" "+r.URL.Path+" "+r.PostForm.Encode()+" #"+strconv.Itoa(call))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// serve поднимает эмулятор с одним обработчиком на все маршруты
+func serve(t *testing.T, handler gin.HandlerFunc) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.NoRoute(handler)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func send(t *testing.T, baseURL, method, path, form string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, baseURL+path, strings.NewReader(form))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("robot", "secret")
+	if form != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robot.jsonl")
+	upstream := newUpstream(t)
+
+	recorder, err := NewRecorder(upstream.URL, path)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	recording := serve(t, recorder.Handle)
+	requests := []struct{ method, path, form string }{
+		{http.MethodGet, "/server", ""},
+		{http.MethodGet, "/server", ""},
+		{http.MethodPost, "/server/321", "server_name=first"},
+		{http.MethodPost, "/server/321", "server_name=second"},
+	}
+	recorded := make([]string, len(requests))
+	for i, r := range requests {
+		status, body := send(t, recording.URL, r.method, r.path, r.form)
+		if status != http.StatusOK {
+			t.Fatalf("record %s %s: status = %d, body %s", r.method, r.path, status, body)
+		}
+		recorded[i] = body
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != len(requests) {
+		t.Errorf("cassette has %d lines, want %d", lines, len(requests))
+	}
+	if strings.Contains(string(data), "Basic ") || strings.Contains(string(data), "secret") {
+		t.Error("cassette contains credentials")
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("NewReplayer: %v", err)
+	}
+	replaying := serve(t, replayer.Handle)
+	tests := []struct {
+		name, method, path, form string
+		want                     string
+	}{
+		{"first of repeated responses", http.MethodGet, "/server", "", recorded[0]},
+		{"repeated responses in recorded order", http.MethodGet, "/server", "", recorded[1]},
+		{"last response repeats", http.MethodGet, "/server", "", recorded[1]},
+		{"matched by form", http.MethodPost, "/server/321", "server_name=second", recorded[3]},
+		{"other form", http.MethodPost, "/server/321", "server_name=first", recorded[2]},
+	}
+	for _, tt := range tests {
+		status, body := send(t, replaying.URL, tt.method, tt.path, tt.form)
+		if status != http.StatusOK || body != tt.want {
+			t.Errorf("%s: status = %d, body %q, want %q", tt.name, status, body, tt.want)
+		}
+	}
+
+	misses := []struct{ method, path, form string }{
+		{http.MethodGet, "/reset", ""},
+		{http.MethodPost, "/server", ""},
+		{http.MethodPost, "/server/321", "server_name=third"},
+	}
+	for _, miss := range misses {
+		status, body := send(t, replaying.URL, miss.method, miss.path, miss.form)
+		if status != http.StatusNotImplemented || !strings.Contains(body, "CASSETTE_MISS") {
+			t.Errorf("%s %s %s: status = %d, body %s, want 501 CASSETTE_MISS", miss.method, miss.path, miss.form, status, body)
+		}
+	}
+}
+
+func TestRequestKeyIgnoresParameterOrder(t *testing.T) {
+	newKey := func(form string) string {
+		req := httptest.NewRequest(http.MethodPost, "/server/321?b=2&a=1", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorded, _, err := NewRequest(req)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		return recorded.Key()
+	}
+	if newKey("x=1&y=2") != newKey("y=2&x=1") {
+		t.Error("form parameter order changes the key")
+	}
+	if newKey("x=1") == newKey("x=2") {
+		t.Error("different forms share a key")
+	}
+}
+
+func TestRecorderUpstreamUnavailable(t *testing.T) {
+	upstream := newUpstream(t)
+	upstream.Close()
+
+	recorder, err := NewRecorder(upstream.URL, filepath.Join(t.TempDir(), "robot.jsonl"))
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	t.Cleanup(func() { recorder.Close() })
+	status, body := send(t, serve(t, recorder.Handle).URL, http.MethodGet, "/server", "")
+	if status != http.StatusBadGateway || !strings.Contains(body, "UPSTREAM_ERROR") {
+		t.Errorf("status = %d, body %s, want 502 UPSTREAM_ERROR", status, body)
+	}
+}
@@ -0,0 +1,114 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recorder проксирует запросы в настоящий Robot и дописывает пары запрос/ответ в кассету
+type Recorder struct {
+	upstream *url.URL
+	client   *http.Client
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder открывает кассету на дозапись
+func NewRecorder(upstream string, path string) (*Recorder, error) {
+	target, err := url.Parse(strings.TrimRight(upstream, "/"))
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		upstream: target,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		file:     file,
+	}, nil
+}
+
+// Close закрывает файл кассеты
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// Handle обработчик Gin для всех маршрутов
+func (r *Recorder) Handle(c *gin.Context) {
+	recorded, body, err := NewRequest(c.Request)
+	if err != nil {
+		middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+		return
+	}
+
+	target := *r.upstream
+	target.Path = r.upstream.Path + c.Request.URL.Path
+	target.RawQuery = c.Request.URL.RawQuery
+
+	outbound, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		middlewares.RespondWithError(c, http.StatusBadGateway, "UPSTREAM_ERROR", err.Error())
+		return
+	}
+	for _, header := range []string{"Authorization", "Content-Type", "Accept"} {
+		if value := c.GetHeader(header); value != "" {
+			outbound.Header.Set(header, value)
+		}
+	}
+
+	resp, err := r.client.Do(outbound)
+	if err != nil {
+		log.Printf("Upstream request failed: %v", err)
+		middlewares.RespondWithError(c, http.StatusBadGateway, "UPSTREAM_ERROR", "Upstream request failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		middlewares.RespondWithError(c, http.StatusBadGateway, "UPSTREAM_ERROR", "Failed to read upstream response")
+		return
+	}
+
+	interaction := Interaction{
+		Request: recorded,
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        string(respBody),
+		},
+		RecordedAt: time.Now().UTC(),
+	}
+	if err := r.append(interaction); err != nil {
+		log.Printf("Failed to write cassette: %v", err)
+	}
+
+	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+}
+
+// append дописывает взаимодействие в кассету одной строкой
+func (r *Recorder) append(interaction Interaction) error {
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(append(line, '\n'))
+	return err
+}
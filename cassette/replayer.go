@@ -0,0 +1,71 @@
+package cassette
+
+import (
+	"net/http"
+	"sync"
+
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Replayer отвечает записанными ответами, сопоставляя запросы по методу, пути и параметрам формы.
+// Несколько записей с одинаковым ключом отдаются по очереди, последняя повторяется
+type Replayer struct {
+	mu       sync.Mutex
+	byKey    map[string][]Interaction
+	position map[string]int
+}
+
+// NewReplayer загружает кассету
+func NewReplayer(path string) (*Replayer, error) {
+	interactions, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	replayer := &Replayer{byKey: map[string][]Interaction{}, position: map[string]int{}}
+	for _, interaction := range interactions {
+		key := interaction.Request.Key()
+		replayer.byKey[key] = append(replayer.byKey[key], interaction)
+	}
+	return replayer, nil
+}
+
+// next возвращает следующий записанный ответ для ключа
+func (r *Replayer) next(key string) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recorded, ok := r.byKey[key]
+	if !ok {
+		return Interaction{}, false
+	}
+	i := r.position[key]
+	if i < len(recorded)-1 {
+		r.position[key] = i + 1
+	}
+	return recorded[i], true
+}
+
+// Handle обработчик Gin для всех маршрутов
+func (r *Replayer) Handle(c *gin.Context) {
+	request, _, err := NewRequest(c.Request)
+	if err != nil {
+		middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+		return
+	}
+
+	interaction, ok := r.next(request.Key())
+	if !ok {
+		middlewares.RespondWithError(c, http.StatusNotImplemented, "CASSETTE_MISS", "No recorded response for "+request.Method+" "+request.Path)
+		return
+	}
+
+	response := interaction.Response
+	if response.Body == "" {
+		c.Status(response.Status)
+		return
+	}
+	c.Data(response.Status, response.ContentType, []byte(response.Body))
+}
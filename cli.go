@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"hetzner-api-emulator/config"
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/fixtures"
+	"hetzner-api-emulator/logger"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+
+	"gorm.io/gorm"
+)
+
+// command подкоманда CLI. Все команды, кроме serve, работают с базой из конфигурации и завершаются
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+// commands список подкоманд в порядке вывода в справке
+func commands() []command {
+	return []command{
+		{"serve", "serve [flags]", "Run the emulator (default when no command is given); needs migrate up or -auto-migrate=true", runServe},
+		{"migrate", "migrate up|down|status [flags]", "Apply, roll back or list schema migrations", runMigrate},
+		{"seed", "seed <fixtures> [flags]", "Load users and servers from a YAML or JSON fixtures file, updating existing ones", runSeed},
+		{"user", "user add|list|passwd ...", "Manage users", runUser},
+		{"server", "server add|list|rm ...", "Manage servers", runServer},
+		{"export", "export [-o file] [-format yaml|json]", "Write all users and servers as a fixtures document", runExport},
+		{"import", "import <file> [flags]", "Replace all users and servers with a fixtures document", runImport},
+	}
+}
+
+func main() {
+	// Без команды или с флагом первым аргументом запускается эмулятор, как раньше
+	args := os.Args[1:]
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage()
+		return
+	}
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			// Ошибку печатаем напрямую: после настройки slog вывод log фильтруется по уровню журнала
+			if err := cmd.run(args); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	printUsage()
+	os.Exit(2)
+}
+
+// printUsage печатает список команд
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: hetzner-api-emulator <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	for _, cmd := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-38s %s\n", cmd.usage, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Every command accepts the configuration flags, see <command> -h.")
+}
+
+// configError оформляет ошибки конфигурации: каждая на своей строке
+func configError(err error) error {
+	return fmt.Errorf("invalid configuration:\n%w", err)
+}
+
+// newTableWriter выравнивает вывод списков по столбцам
+func newTableWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+// parseInterleaved разбирает флаги, которые могут стоять и после позиционных аргументов, и возвращает позиционные
+func parseInterleaved(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// openDatabase разбирает флаги команды вместе с конфигурацией и подключается к базе.
+// База в памяти для команд бессмысленна: всё записанное пропадёт при выходе
+func openDatabase(fs *flag.FlagSet, args []string) (*config.Config, *gorm.DB, []string, error) {
+	loader := config.Register(fs)
+	positional, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, nil, nil, configError(err)
+	}
+	if err := logger.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := models.SetBcryptCost(cfg.BcryptCost); err != nil {
+		return nil, nil, nil, err
+	}
+	if cfg.Database.IsInMemory() {
+		return nil, nil, nil, errors.New("the database is in memory, set db_connection and db_name to a persistent database")
+	}
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cfg, db, positional, nil
+}
+
+// openState как openDatabase, но ещё требует актуальную схему: команды с данными не работают со старой
+func openState(fs *flag.FlagSet, args []string) (*gorm.DB, []string, error) {
+	_, db, positional, err := openDatabase(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pending > 0 {
+		return nil, nil, fmt.Errorf("schema is behind by %d migrations, run migrate up first", pending)
+	}
+	return db, positional, nil
+}
+
+// runSeed загружает фикстуры поверх текущего состояния
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: seed <fixtures>")
+	}
+
+	if err := fixtures.LoadFile(db, positional[0]); err != nil {
+		return fmt.Errorf("failed to load fixtures from %s: %w", positional[0], err)
+	}
+	log.Printf("Fixtures loaded from %s", positional[0])
+	return nil
+}
+
+// runExport выгружает пользователей и серверы в stdout или файл
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	outputFlag := fs.String("o", "", "Output file (default: stdout)")
+	formatFlag := fs.String("format", "", "yaml or json (default: from the output file extension, otherwise yaml)")
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errors.New("usage: export [-o file] [-format yaml|json]")
+	}
+
+	format := *formatFlag
+	if format == "" {
+		format = "yaml"
+		if strings.ToLower(filepath.Ext(*outputFlag)) == ".json" {
+			format = "json"
+		}
+	}
+
+	doc, err := fixtures.Export(db)
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	data, err := fixtures.Marshal(doc, format)
+	if err != nil {
+		return err
+	}
+
+	if *outputFlag == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*outputFlag, data, 0o600); err != nil {
+		return err
+	}
+	log.Printf("Exported %d users and %d servers to %s", len(doc.Users), len(doc.Servers), *outputFlag)
+	return nil
+}
+
+// runImport заменяет пользователей и серверы содержимым файла
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: import <file>")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	doc, err := fixtures.Parse(positional[0], data)
+	if err != nil {
+		return err
+	}
+	if err := fixtures.Replace(db, doc); err != nil {
+		return fmt.Errorf("failed to import %s: %w", positional[0], err)
+	}
+	log.Printf("Imported %d users and %d servers from %s", len(doc.Users), len(doc.Servers), positional[0])
+	return nil
+}
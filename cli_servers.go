@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+
+	"hetzner-api-emulator/models"
+
+	"gorm.io/gorm"
+)
+
+// runServer выполняет команды server add|list|rm
+func runServer(args []string) error {
+	usage := "usage: server add <server-number> -user u -product p -dc dc [-name n] [-ip a] | server list [-user u] [-all] | server rm <server-number>"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "add":
+		return runServerAdd(args[1:])
+	case "list":
+		return runServerList(args[1:])
+	case "rm":
+		return runServerRemove(args[1:])
+	default:
+		return fmt.Errorf("unknown server command %q, %s", args[0], usage)
+	}
+}
+
+// runServerAdd создаёт сервер; продукт и датацентр проверяются по каталогу
+func runServerAdd(args []string) error {
+	fs := flag.NewFlagSet("server add", flag.ExitOnError)
+	userFlag := fs.String("user", "", "Owner username (required)")
+	productFlag := fs.String("product", "", "Product from the catalog, e.g. AX41 (required)")
+	dcFlag := fs.String("dc", "", "Datacenter from the catalog, e.g. FSN1-DC14 (required)")
+	nameFlag := fs.String("name", "", "Server name")
+	ipFlag := fs.String("ip", "", "Main IPv4 or IPv6 address")
+	ipv6NetFlag := fs.String("ipv6-net", "", "IPv6 subnet, e.g. 2a01:4f8:111:4221::")
+	paidUntilFlag := fs.String("paid-until", "", "Paid until date, yyyy-MM-dd")
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: server add <server-number> -user u -product p -dc dc [flags]")
+	}
+	serverNumber, err := parseServerNumber(positional[0])
+	if err != nil {
+		return err
+	}
+	if *userFlag == "" || *productFlag == "" || *dcFlag == "" {
+		return errors.New("-user, -product and -dc are required")
+	}
+
+	spec := models.ServerSpec{
+		ServerNumber:  serverNumber,
+		Username:      *userFlag,
+		ServerName:    *nameFlag,
+		ServerIP:      *ipFlag,
+		ServerIPv6Net: *ipv6NetFlag,
+		Product:       *productFlag,
+		DC:            *dcFlag,
+		PaidUntil:     *paidUntilFlag,
+	}
+	if *ipFlag != "" {
+		ip := net.ParseIP(*ipFlag)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address %q", *ipFlag)
+		}
+		mask := "32"
+		if ip.To4() == nil {
+			mask = "128"
+		}
+		spec.IPs = []models.IPSpec{{IP: *ipFlag, Mask: mask}}
+	}
+
+	// Проверка и вставка в одной транзакции: UpsertServer сам по себе перезаписал бы чужой сервер.
+	// Если параллельная команда успела вставить тот же номер, уникальный индекс отклонит вставку
+	exists := fmt.Errorf("server %d already exists", serverNumber)
+	var server *models.Server
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&models.Server{}).Where("server_number = ?", serverNumber).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return exists
+		}
+		server, err = models.UpsertServer(tx, spec)
+		return err
+	})
+	if models.IsDuplicateKey(db, err) {
+		return exists
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("server_number: %d\n", server.ServerNumber)
+	fmt.Printf("product:       %s\n", server.Product)
+	fmt.Printf("dc:            %s\n", server.DC)
+	return nil
+}
+
+// runServerList печатает серверы, при -all вместе со снятыми после даты отмены
+func runServerList(args []string) error {
+	fs := flag.NewFlagSet("server list", flag.ExitOnError)
+	userFlag := fs.String("user", "", "Only servers of this user")
+	allFlag := fs.Bool("all", false, "Include servers removed after their cancellation date")
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errors.New("usage: server list [-user u] [-all]")
+	}
+
+	query := db.Model(&models.Server{})
+	if *allFlag {
+		query = query.Unscoped()
+	}
+	if *userFlag != "" {
+		userID, err := findUserID(db, *userFlag)
+		if err != nil {
+			return err
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	var servers []models.Server
+	if err := query.Order("server_number").Find(&servers).Error; err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := db.Find(&users).Error; err != nil {
+		return err
+	}
+	usernames := make(map[int]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	w := newTableWriter()
+	fmt.Fprintln(w, "NUMBER\tNAME\tUSER\tPRODUCT\tDC\tIP\tSTATUS\tCANCELLATION")
+	for _, server := range servers {
+		status := server.Status
+		if server.DeletedAt.Valid {
+			status = "gone"
+		}
+		cancellation := "-"
+		if server.Cancelled && server.CancellationDate != nil {
+			cancellation = server.CancellationDate.Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", server.ServerNumber, server.ServerName, usernames[server.UserID],
+			server.Product, server.DC, server.ServerIP, status, cancellation)
+	}
+	return w.Flush()
+}
+
+// runServerRemove удаляет сервер и его IP-адреса
+func runServerRemove(args []string) error {
+	fs := flag.NewFlagSet("server rm", flag.ExitOnError)
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: server rm <server-number>")
+	}
+	serverNumber, err := parseServerNumber(positional[0])
+	if err != nil {
+		return err
+	}
+
+	var server models.Server
+	if err := db.Unscoped().Where("server_number = ?", serverNumber).Limit(1).Find(&server).Error; err != nil {
+		return err
+	}
+	if server.ID == 0 {
+		return fmt.Errorf("server %d not found", serverNumber)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("server_id = ?", server.ID).Delete(&models.IP{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&server).Error
+	})
+}
+
+// parseServerNumber разбирает номер сервера из аргумента команды
+func parseServerNumber(value string) (int, error) {
+	serverNumber, err := strconv.Atoi(value)
+	if err != nil || serverNumber <= 0 {
+		return 0, fmt.Errorf("invalid server number %q", value)
+	}
+	return serverNumber, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// newDatabaseFlags возвращает флаги файловой базы SQLite во временном каталоге
+func newDatabaseFlags(t *testing.T) []string {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	path := filepath.Join(t.TempDir(), "emulator.db")
+	return []string{"-db-connection=sqlite", "-db-name=" + path, "-bcrypt-cost=4", "-log-level=error"}
+}
+
+// runCommand выполняет команду CLI и возвращает её вывод в stdout
+func runCommand(t *testing.T, run func([]string) error, args ...string) (string, error) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, reader)
+		output <- buf.String()
+	}()
+
+	err = run(args)
+	os.Stdout = stdout
+	writer.Close()
+	return <-output, err
+}
+
+// mustRun выполняет команду и останавливает тест при ошибке
+func mustRun(t *testing.T, run func([]string) error, args ...string) string {
+	t.Helper()
+	out, err := runCommand(t, run, args...)
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return out
+}
+
+// openTestDatabase открывает базу из флагов, чтобы проверить результат команд
+func openTestDatabase(t *testing.T, flags []string) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(strings.TrimPrefix(flags[1], "-db-name="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// newMigratedFlags как newDatabaseFlags, но с применёнными миграциями
+func newMigratedFlags(t *testing.T) []string {
+	t.Helper()
+	flags := newDatabaseFlags(t)
+	mustRun(t, runMigrate, append([]string{"up"}, flags...)...)
+	return flags
+}
+
+func TestServeRefusesUnmigratedDatabase(t *testing.T) {
+	flags := newDatabaseFlags(t)
+	_, err := runCommand(t, runServe, flags...)
+	if err == nil || !strings.Contains(err.Error(), "run migrate up first or start serve with -auto-migrate=true") {
+		t.Fatalf("serve = %v, want a refusal to start without a schema", err)
+	}
+
+	// Команды с данными тоже не работают без схемы
+	if _, err := runCommand(t, runUser, append([]string{"list"}, flags...)...); err == nil || !strings.Contains(err.Error(), "run migrate up first") {
+		t.Errorf("user list = %v, want a refusal without a schema", err)
+	}
+}
+
+func TestServeAutoMigrate(t *testing.T) {
+	// Занятый порт останавливает serve сразу после миграций, вместо того чтобы слушать бесконечно
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	flags := newDatabaseFlags(t)
+	_, err = runCommand(t, runServe, append(flags, "-auto-migrate=true", "-host=127.0.0.1", "-port="+port, "-lifecycle-interval=0")...)
+	if err == nil || !strings.Contains(err.Error(), "server failed to start") {
+		t.Fatalf("serve = %v, want it to migrate and then fail on the busy port", err)
+	}
+
+	db := openTestDatabase(t, flags)
+	if pending, err := migrations.Pending(db); err != nil || pending != 0 {
+		t.Errorf("pending migrations = %d, %v, want the schema migrated", pending, err)
+	}
+}
+
+func TestUserCommands(t *testing.T) {
+	flags := newMigratedFlags(t)
+	user := func(args ...string) (string, error) {
+		return runCommand(t, runUser, append(args, flags...)...)
+	}
+
+	out, err := user("add", "alice", "-password=secret", "-scopes=server:read", "-allowed-ips=203.0.113.0/24")
+	if err != nil || !strings.Contains(out, "username: alice") || strings.Contains(out, "password:") {
+		t.Fatalf("user add = %q, %v", out, err)
+	}
+	if _, err := user("add", "alice", "-password=other"); err == nil || !strings.Contains(err.Error(), `user "alice" already exists`) {
+		t.Errorf("second user add = %v, want already exists", err)
+	}
+
+	// Без пароля он генерируется и печатается один раз
+	out, err = user("add", "-generate-username", "-disabled")
+	if err != nil || !strings.Contains(out, "username: #ws+") || !strings.Contains(out, "password: ") {
+		t.Fatalf("user add -generate-username = %q, %v", out, err)
+	}
+
+	out, err = user("list")
+	if err != nil {
+		t.Fatalf("user list: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "alice") || !strings.Contains(lines[1], "server:read") ||
+		!strings.Contains(lines[1], "203.0.113.0/24") || !strings.Contains(lines[2], "disabled") {
+		t.Errorf("user list =\n%s", out)
+	}
+
+	if _, err := user("passwd", "alice", "-password=changed"); err != nil {
+		t.Fatalf("user passwd: %v", err)
+	}
+	db := openTestDatabase(t, flags)
+	var stored models.User
+	if err := db.Where("username = ?", "alice").First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("changed")) != nil {
+		t.Error("user passwd did not change the password")
+	}
+	if _, err := user("passwd", "bob"); err == nil || !strings.Contains(err.Error(), `user "bob" not found`) {
+		t.Errorf("user passwd bob = %v, want not found", err)
+	}
+}
+
+func TestServerCommands(t *testing.T) {
+	flags := newMigratedFlags(t)
+	mustRun(t, runUser, append([]string{"add", "alice", "-password=secret"}, flags...)...)
+	mustRun(t, runUser, append([]string{"add", "bob", "-password=secret"}, flags...)...)
+	server := func(args ...string) (string, error) {
+		return runCommand(t, runServer, append(args, flags...)...)
+	}
+
+	out, err := server("add", "321", "-user=alice", "-product=AX41", "-dc=FSN1-DC14", "-name=web1", "-ip=203.0.113.10")
+	if err != nil || !strings.Contains(out, "server_number: 321") {
+		t.Fatalf("server add = %q, %v", out, err)
+	}
+	if _, err := server("add", "421", "-user=bob", "-product=DS 3000", "-dc=NBG1-DC1", "-ip=2a01:4f8:111:4221::2"); err != nil {
+		t.Fatalf("server add 421: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"taken number", []string{"add", "321", "-user=bob", "-product=AX41", "-dc=FSN1-DC14"}, "server 321 already exists"},
+		{"unknown product", []string{"add", "521", "-user=bob", "-product=AX1000", "-dc=FSN1-DC14"}, "AX1000"},
+		{"unknown user", []string{"add", "521", "-user=carol", "-product=AX41", "-dc=FSN1-DC14"}, `user "carol" not found`},
+		{"invalid address", []string{"add", "521", "-user=bob", "-product=AX41", "-dc=FSN1-DC14", "-ip=300.0.0.1"}, "invalid IP address"},
+		{"missing flags", []string{"add", "521", "-user=bob"}, "-user, -product and -dc are required"},
+		{"invalid number", []string{"rm", "abc"}, `invalid server number "abc"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := server(tt.args...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("server %v = %v, want %q", tt.args, err, tt.want)
+			}
+		})
+	}
+
+	out, err = server("list")
+	if err != nil {
+		t.Fatalf("server list: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "web1") || !strings.Contains(lines[1], "alice") || !strings.Contains(lines[2], "bob") {
+		t.Errorf("server list =\n%s", out)
+	}
+	if out, err := server("list", "-user=bob"); err != nil || strings.Contains(out, "alice") || !strings.Contains(out, "421") {
+		t.Errorf("server list -user=bob =\n%s, %v", out, err)
+	}
+
+	if _, err := server("rm", "321"); err != nil {
+		t.Fatalf("server rm: %v", err)
+	}
+	if _, err := server("rm", "321"); err == nil || !strings.Contains(err.Error(), "server 321 not found") {
+		t.Errorf("second server rm = %v, want not found", err)
+	}
+	if out, err := server("list", "-all"); err != nil || strings.Contains(out, "web1") {
+		t.Errorf("server list -all after rm =\n%s, %v", out, err)
+	}
+
+	// Номер освободился, и IP-адреса сервера удалены вместе с ним
+	if _, err := server("add", "321", "-user=bob", "-product=AX41", "-dc=FSN1-DC14", "-ip=203.0.113.10"); err != nil {
+		t.Fatalf("server add after rm: %v", err)
+	}
+	db := openTestDatabase(t, flags)
+	var ips int64
+	if err := db.Model(&models.IP{}).Where("ip_address = ?", "203.0.113.10").Count(&ips).Error; err != nil || ips != 1 {
+		t.Errorf("IPs with 203.0.113.10 = %d, %v, want 1", ips, err)
+	}
+}
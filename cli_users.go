@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"hetzner-api-emulator/models"
+
+	"gorm.io/gorm"
+)
+
+// runUser выполняет команды user add|list|passwd
+func runUser(args []string) error {
+	usage := "usage: user add <username> [-password p] [-scopes s] [-allowed-ips a] [-disabled] | user add -generate-username | user list | user passwd <username> [-password p]"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "add":
+		return runUserAdd(args[1:])
+	case "list":
+		return runUserList(args[1:])
+	case "passwd":
+		return runUserPasswd(args[1:])
+	default:
+		return fmt.Errorf("unknown user command %q, %s", args[0], usage)
+	}
+}
+
+// runUserAdd создаёт пользователя. Если пароль не задан, он генерируется и печатается один раз
+func runUserAdd(args []string) error {
+	fs := flag.NewFlagSet("user add", flag.ExitOnError)
+	passwordFlag := fs.String("password", "", "Password (default: generated and printed once)")
+	generateFlag := fs.Bool("generate-username", false, "Generate a web service username like #ws+XXXXXXXX")
+	scopesFlag := fs.String("scopes", "", "Permission scopes separated by commas, e.g. server:read,reset:write (default: full access)")
+	allowedIPsFlag := fs.String("allowed-ips", "", "Allowed addresses and CIDR ranges separated by commas (default: any)")
+	disabledFlag := fs.Bool("disabled", false, "Create the account disabled")
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var username string
+	switch {
+	case *generateFlag && len(positional) == 0:
+		username, err = models.GenerateUsername(db)
+		if err != nil {
+			return fmt.Errorf("failed to generate username: %w", err)
+		}
+	case !*generateFlag && len(positional) == 1:
+		username = positional[0]
+	default:
+		return errors.New("usage: user add <username> [flags] or user add -generate-username [flags]")
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("user %q already exists", username)
+	}
+
+	password := *passwordFlag
+	generated := password == ""
+	if generated {
+		if password, err = models.GeneratePassword(); err != nil {
+			return fmt.Errorf("failed to generate password: %w", err)
+		}
+	}
+	hashedPassword, err := models.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user := models.User{Username: username, Password: hashedPassword, Disabled: *disabledFlag}
+	if err := user.SetScopes(splitList(*scopesFlag)); err != nil {
+		return err
+	}
+	if err := user.SetAllowedIPs(splitList(*allowedIPsFlag)); err != nil {
+		return err
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	fmt.Printf("id:       %d\n", user.ID)
+	fmt.Printf("username: %s\n", user.Username)
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+// runUserList печатает пользователей
+func runUserList(args []string) error {
+	fs := flag.NewFlagSet("user list", flag.ExitOnError)
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errors.New("usage: user list")
+	}
+
+	var users []models.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+
+	w := newTableWriter()
+	fmt.Fprintln(w, "ID\tUSERNAME\tSTATUS\tSCOPES\tALLOWED IPS\tCREATED AT")
+	for _, user := range users {
+		status := "active"
+		if user.Disabled {
+			status = "disabled"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", user.ID, user.Username, status,
+			listOrDefault(user.ScopeList(), "all"), listOrDefault(user.AllowedIPList(), "any"),
+			user.CreatedAt.UTC().Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+// runUserPasswd меняет пароль пользователя. Если пароль не задан, он генерируется и печатается один раз
+func runUserPasswd(args []string) error {
+	fs := flag.NewFlagSet("user passwd", flag.ExitOnError)
+	passwordFlag := fs.String("password", "", "New password (default: generated and printed once)")
+	db, positional, err := openState(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: user passwd <username> [-password p]")
+	}
+
+	var user models.User
+	if err := db.Where("username = ?", positional[0]).Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return fmt.Errorf("user %q not found", positional[0])
+	}
+
+	password := *passwordFlag
+	generated := password == ""
+	if generated {
+		if password, err = models.GeneratePassword(); err != nil {
+			return fmt.Errorf("failed to generate password: %w", err)
+		}
+	}
+	hashedPassword, err := models.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(&user).UpdateColumn("password", hashedPassword).Error; err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if generated {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+// findUserID возвращает идентификатор пользователя по имени
+func findUserID(db *gorm.DB, username string) (int, error) {
+	var user models.User
+	if err := db.Where("username = ?", username).Limit(1).Find(&user).Error; err != nil {
+		return 0, err
+	}
+	if user.ID == 0 {
+		return 0, fmt.Errorf("user %q not found", username)
+	}
+	return user.ID, nil
+}
+
+// splitList разбирает список, разделённый запятыми или пробелами
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// listOrDefault выводит список через запятую или значение для пустого списка
+func listOrDefault(items []string, empty string) string {
+	if len(items) == 0 {
+		return empty
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,132 @@
+// Package client типизированный клиент Robot API в объёме, который реализует эмулятор.
+// Подходит и для настоящего Robot, и для эмулятора, в том числе встроенного:
+//
+//	emu, _ := emulator.New(emulator.Options{})
+//	emu.AddUser("test", "secret")
+//	robot := client.New(emu.URL, "test", "secret")
+//	servers, err := robot.ListServers(ctx)
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"hetzner-api-emulator/middlewares"
+)
+
+// DefaultBaseURL адрес настоящего Robot Webservice
+const DefaultBaseURL = "https://robot-ws.your-server.de"
+
+// Route маршрут Robot, для которого у клиента есть метод
+type Route struct {
+	Method string
+	Path   string // В формате Gin: /server/:server-number
+}
+
+// Routes все маршруты, которые покрывает клиент. Пакет conformance сверяет их с маршрутами эмулятора
+var Routes = []Route{
+	{Method: http.MethodGet, Path: "/server"},
+	{Method: http.MethodGet, Path: "/server/:server-number"},
+	{Method: http.MethodPost, Path: "/server/:server-number"},
+	{Method: http.MethodGet, Path: "/server/:server-number/cancellation"},
+	{Method: http.MethodPost, Path: "/server/:server-number/cancellation"},
+	{Method: http.MethodDelete, Path: "/server/:server-number/cancellation"},
+}
+
+// Error ошибка Robot из ответа: статус, код, сообщение и, если есть, missing/invalid или max_request/interval
+type Error = middlewares.RobotError
+
+// Client клиент Robot API с Basic-аутентификацией
+type Client struct {
+	BaseURL    string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// New создаёт клиент; пустой baseURL означает настоящий Robot
+func New(baseURL, username, password string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Username:   username,
+		Password:   password,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// IsErrorCode сообщает, что err — ошибка Robot с указанным кодом
+func IsErrorCode(err error, code string) bool {
+	var robotErr *Error
+	return errors.As(err, &robotErr) && robotErr.Code == code
+}
+
+// do выполняет запрос с параметрами формы и разбирает JSON-ответ в out (если он не nil)
+func (c *Client) do(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(c.Username, c.Password)
+
+	return c.send(req, out)
+}
+
+// send отправляет запрос и разбирает ответ или ошибку Robot
+func (c *Client) send(req *http.Request, out interface{}) error {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp.StatusCode, data)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
+
+// decodeError разбирает конверт ошибки Robot; если тело не в этом формате, ошибка строится по статусу
+func decodeError(status int, data []byte) error {
+	var envelope struct {
+		Error *Error `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Error == nil || envelope.Error.Code == "" {
+		return &Error{Status: status, Code: http.StatusText(status), Message: strings.TrimSpace(string(data))}
+	}
+	if envelope.Error.Status == 0 {
+		envelope.Error.Status = status
+	}
+	return envelope.Error
+}
@@ -0,0 +1,134 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/client"
+	"hetzner-api-emulator/emulator"
+	"hetzner-api-emulator/models"
+)
+
+func newEmulator(t *testing.T) *emulator.Emulator {
+	t.Helper()
+	emu, err := emulator.New(emulator.Options{})
+	if err != nil {
+		t.Fatalf("emulator.New: %v", err)
+	}
+	t.Cleanup(emu.Close)
+	if _, err := emu.AddUser("test", "secret"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	_, err = emu.AddServer(models.ServerSpec{
+		ServerNumber: 321, Username: "test", ServerName: "web1", ServerIP: "203.0.113.10", Product: "AX41", DC: "FSN1-DC14",
+		ReservationPossible: true, IPs: []models.IPSpec{{IP: "203.0.113.10", Mask: "32"}},
+	})
+	if err != nil {
+		t.Fatalf("AddServer: %v", err)
+	}
+	emu.Clock.Freeze()
+	emu.Clock.Set(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	return emu
+}
+
+func TestClientServers(t *testing.T) {
+	ctx := context.Background()
+	robot := client.New(newEmulator(t).URL, "test", "secret")
+
+	servers, err := robot.ListServers(ctx)
+	if err != nil {
+		t.Fatalf("ListServers: %v", err)
+	}
+	if len(servers) != 1 || servers[0].Server.ServerNumber != 321 || !reflect.DeepEqual(servers[0].Server.IP, []string{"203.0.113.10"}) {
+		t.Errorf("ListServers = %+v", servers)
+	}
+
+	server, err := robot.GetServer(ctx, 321)
+	if err != nil {
+		t.Fatalf("GetServer: %v", err)
+	}
+	if server.Server.ServerName != "web1" || server.Server.Product != "AX41" || server.Server.DC != "FSN1-DC14" || server.Server.Traffic != "unlimited" {
+		t.Errorf("GetServer = %+v", server.Server)
+	}
+
+	renamed, err := robot.UpdateServerName(ctx, 321, "web2")
+	if err != nil {
+		t.Fatalf("UpdateServerName: %v", err)
+	}
+	if renamed.Server.ServerName != "web2" {
+		t.Errorf("server_name = %q, want web2", renamed.Server.ServerName)
+	}
+}
+
+func TestClientCancellation(t *testing.T) {
+	ctx := context.Background()
+	robot := client.New(newEmulator(t).URL, "test", "secret")
+
+	cancellation, err := robot.GetCancellation(ctx, 321)
+	if err != nil {
+		t.Fatalf("GetCancellation: %v", err)
+	}
+	if cancellation.Cancellation.Cancelled || cancellation.Cancellation.EarliestCancellationDate != "2030-01-08" {
+		t.Errorf("GetCancellation = %+v", cancellation.Cancellation)
+	}
+
+	reserve := true
+	cancelled, err := robot.CancelServer(ctx, 321, client.CancelServerRequest{
+		CancellationDate: "2030-01-31", CancellationReason: "Upgrade to a new server", ReserveLocation: &reserve,
+	})
+	if err != nil {
+		t.Fatalf("CancelServer: %v", err)
+	}
+	got := cancelled.Cancellation
+	if !got.Cancelled || got.CancellationDate == nil || *got.CancellationDate != "2030-01-31" || !got.Reserved || got.CancellationReason != "Upgrade to a new server" {
+		t.Errorf("CancelServer = %+v", got)
+	}
+
+	if err := robot.WithdrawCancellation(ctx, 321); err != nil {
+		t.Fatalf("WithdrawCancellation: %v", err)
+	}
+	if cancellation, err := robot.GetCancellation(ctx, 321); err != nil || cancellation.Cancellation.Cancelled {
+		t.Errorf("after withdrawal: %+v, %v", cancellation, err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	emu := newEmulator(t)
+
+	_, err := client.New(emu.URL, "test", "wrong").ListServers(ctx)
+	var robotErr *client.Error
+	if !errors.As(err, &robotErr) || robotErr.Status != http.StatusUnauthorized || robotErr.Code == "" {
+		t.Errorf("wrong password: %#v, want a 401 Robot error", err)
+	}
+
+	robot := client.New(emu.URL, "test", "secret")
+	_, err = robot.GetServer(ctx, 999)
+	if !client.IsErrorCode(err, "SERVER_NOT_FOUND") || !errors.As(err, &robotErr) || robotErr.Status != http.StatusNotFound {
+		t.Errorf("unknown server: %#v, want a 404 SERVER_NOT_FOUND", err)
+	}
+
+	_, err = robot.CancelServer(ctx, 321, client.CancelServerRequest{CancellationDate: "31.01.2030"})
+	if !errors.As(err, &robotErr) || robotErr.Code != "INVALID_INPUT" || !reflect.DeepEqual(robotErr.Invalid, []string{"cancellation_date"}) {
+		t.Errorf("invalid date: %#v, want INVALID_INPUT for cancellation_date", err)
+	}
+}
+
+func TestClientNonRobotError(t *testing.T) {
+	// Ответ прокси или балансировщика не в формате Robot
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	t.Cleanup(proxy.Close)
+
+	_, err := client.New(proxy.URL, "test", "secret").ListServers(context.Background())
+	var robotErr *client.Error
+	if !errors.As(err, &robotErr) || robotErr.Status != http.StatusBadGateway || robotErr.Message != "upstream unavailable" {
+		t.Errorf("error = %#v, want a 502 built from the status", err)
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"hetzner-api-emulator/models"
+)
+
+// CancelServerRequest параметры отмены сервера; пустые поля не отправляются
+type CancelServerRequest struct {
+	CancellationDate   string // yyyy-MM-dd; по умолчанию Robot выбирает дату сам
+	CancellationReason string // Одна из models.GetAllCancellationReasons
+	ReserveLocation    *bool
+}
+
+// ListServers возвращает серверы пользователя (GET /server)
+func (c *Client) ListServers(ctx context.Context) ([]models.ServerResponseShort, error) {
+	var servers []models.ServerResponseShort
+	if err := c.do(ctx, http.MethodGet, "/server", nil, &servers); err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// GetServer возвращает сервер по номеру (GET /server/{server-number})
+func (c *Client) GetServer(ctx context.Context, serverNumber int) (*models.ServerResponse, error) {
+	var server models.ServerResponse
+	if err := c.do(ctx, http.MethodGet, serverPath(serverNumber), nil, &server); err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// UpdateServerName меняет имя сервера (POST /server/{server-number})
+func (c *Client) UpdateServerName(ctx context.Context, serverNumber int, serverName string) (*models.ServerResponse, error) {
+	var server models.ServerResponse
+	form := url.Values{"server_name": {serverName}}
+	if err := c.do(ctx, http.MethodPost, serverPath(serverNumber), form, &server); err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// GetCancellation возвращает данные об отмене сервера (GET /server/{server-number}/cancellation)
+func (c *Client) GetCancellation(ctx context.Context, serverNumber int) (*models.CancellationResponse, error) {
+	var cancellation models.CancellationResponse
+	if err := c.do(ctx, http.MethodGet, serverPath(serverNumber)+"/cancellation", nil, &cancellation); err != nil {
+		return nil, err
+	}
+	return &cancellation, nil
+}
+
+// CancelServer отменяет сервер (POST /server/{server-number}/cancellation)
+func (c *Client) CancelServer(ctx context.Context, serverNumber int, req CancelServerRequest) (*models.CancellationResponse, error) {
+	form := url.Values{}
+	if req.CancellationDate != "" {
+		form.Set("cancellation_date", req.CancellationDate)
+	}
+	if req.CancellationReason != "" {
+		form.Set("cancellation_reason", req.CancellationReason)
+	}
+	if req.ReserveLocation != nil {
+		form.Set("reserve_location", strconv.FormatBool(*req.ReserveLocation))
+	}
+
+	var cancellation models.CancellationResponse
+	if err := c.do(ctx, http.MethodPost, serverPath(serverNumber)+"/cancellation", form, &cancellation); err != nil {
+		return nil, err
+	}
+	return &cancellation, nil
+}
+
+// WithdrawCancellation отзывает отмену сервера (DELETE /server/{server-number}/cancellation)
+func (c *Client) WithdrawCancellation(ctx context.Context, serverNumber int) error {
+	return c.do(ctx, http.MethodDelete, serverPath(serverNumber)+"/cancellation", nil, nil)
+}
+
+func serverPath(serverNumber int) string {
+	return "/server/" + strconv.Itoa(serverNumber)
+}
@@ -0,0 +1,129 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock источник текущего времени для обработчиков
+type Clock interface {
+	Now() time.Time
+}
+
+// Virtual часы, которые по умолчанию идут вместе с реальным временем,
+// но их можно заморозить, переставить или перевести вперёд
+type Virtual struct {
+	mu        sync.Mutex
+	offset    time.Duration
+	frozen    bool
+	frozenAt  time.Time
+	listeners []func(now time.Time)
+}
+
+// New создаёт часы, идущие вместе с реальным временем
+func New() *Virtual {
+	return &Virtual{}
+}
+
+// Now возвращает текущее виртуальное время
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.nowLocked()
+}
+
+func (v *Virtual) nowLocked() time.Time {
+	if v.frozen {
+		return v.frozenAt
+	}
+	return time.Now().Add(v.offset)
+}
+
+// Frozen сообщает, остановлены ли часы
+func (v *Virtual) Frozen() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.frozen
+}
+
+// Freeze останавливает часы на текущем виртуальном времени
+func (v *Virtual) Freeze() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.frozen {
+		v.frozenAt = v.nowLocked()
+		v.frozen = true
+	}
+	return v.frozenAt
+}
+
+// Unfreeze запускает часы с того времени, на котором они стояли
+func (v *Virtual) Unfreeze() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.frozen {
+		v.offset = time.Until(v.frozenAt)
+		v.frozen = false
+	}
+	return v.nowLocked()
+}
+
+// Set переставляет часы на указанное время, сохраняя режим (заморожены или идут)
+func (v *Virtual) Set(t time.Time) time.Time {
+	v.mu.Lock()
+	if v.frozen {
+		v.frozenAt = t
+	} else {
+		v.offset = time.Until(t)
+	}
+	now := v.nowLocked()
+	v.mu.Unlock()
+
+	v.notify(now)
+	return now
+}
+
+// Advance переводит часы вперёд на d
+func (v *Virtual) Advance(d time.Duration) time.Time {
+	v.mu.Lock()
+	if v.frozen {
+		v.frozenAt = v.frozenAt.Add(d)
+	} else {
+		v.offset += d
+	}
+	now := v.nowLocked()
+	v.mu.Unlock()
+
+	v.notify(now)
+	return now
+}
+
+// Reset возвращает часы к реальному времени
+func (v *Virtual) Reset() time.Time {
+	v.mu.Lock()
+	v.offset = 0
+	v.frozen = false
+	now := v.nowLocked()
+	v.mu.Unlock()
+
+	v.notify(now)
+	return now
+}
+
+// OnChange регистрирует функцию, которая вызывается после каждой перестановки часов
+func (v *Virtual) OnChange(fn func(now time.Time)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.listeners = append(v.listeners, fn)
+}
+
+// notify вызывает подписчиков вне блокировки, чтобы они могли сами читать часы
+func (v *Virtual) notify(now time.Time) {
+	v.mu.Lock()
+	listeners := append([]func(time.Time){}, v.listeners...)
+	v.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(now)
+	}
+}
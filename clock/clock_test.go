@@ -0,0 +1,83 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtualFollowsRealTime(t *testing.T) {
+	clk := New()
+	before := time.Now()
+	now := clk.Now()
+	if now.Before(before) || now.Sub(before) > time.Second || clk.Frozen() {
+		t.Errorf("Now = %s, frozen %v, want real time", now, clk.Frozen())
+	}
+}
+
+func TestVirtualFreeze(t *testing.T) {
+	clk := New()
+	frozenAt := clk.Freeze()
+	time.Sleep(5 * time.Millisecond)
+	if !clk.Now().Equal(frozenAt) || !clk.Frozen() {
+		t.Fatalf("Now = %s, want %s while frozen", clk.Now(), frozenAt)
+	}
+	if again := clk.Freeze(); !again.Equal(frozenAt) {
+		t.Errorf("second Freeze moved the clock to %s", again)
+	}
+
+	resumed := clk.Unfreeze()
+	if clk.Frozen() || resumed.Sub(frozenAt) > time.Second || resumed.Before(frozenAt) {
+		t.Errorf("Unfreeze = %s, want time to resume from %s", resumed, frozenAt)
+	}
+}
+
+func TestVirtualSetAndAdvance(t *testing.T) {
+	target := time.Date(2030, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	frozen := New()
+	frozen.Freeze()
+	if got := frozen.Set(target); !got.Equal(target) || !frozen.Frozen() {
+		t.Errorf("frozen Set = %s, frozen %v, want %s and still frozen", got, frozen.Frozen(), target)
+	}
+	if got := frozen.Advance(36 * time.Hour); !got.Equal(target.Add(36 * time.Hour)) {
+		t.Errorf("frozen Advance = %s, want %s", got, target.Add(36*time.Hour))
+	}
+
+	// Идущие часы продолжают идти с новой точки
+	running := New()
+	if got := running.Set(target); got.Sub(target) > time.Second || running.Frozen() {
+		t.Errorf("running Set = %s, frozen %v, want %s and running", got, running.Frozen(), target)
+	}
+	if got := running.Advance(24 * time.Hour); got.Sub(target.Add(24*time.Hour)) > time.Second {
+		t.Errorf("running Advance = %s, want about %s", got, target.Add(24*time.Hour))
+	}
+
+	if got := running.Reset(); time.Since(got) > time.Second || got.After(time.Now()) {
+		t.Errorf("Reset = %s, want real time", got)
+	}
+}
+
+func TestVirtualNotifiesListeners(t *testing.T) {
+	clk := New()
+	clk.Freeze()
+	var notified []time.Time
+	clk.OnChange(func(now time.Time) {
+		// Подписчик может читать часы: уведомление идёт вне блокировки
+		if clk.Frozen() && !clk.Now().Equal(now) {
+			t.Errorf("listener got %s, clock shows %s", now, clk.Now())
+		}
+		notified = append(notified, now)
+	})
+
+	target := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk.Set(target)
+	clk.Advance(time.Hour)
+	clk.Freeze()
+	clk.Unfreeze()
+	clk.Reset()
+
+	// Freeze и Unfreeze время не переставляют и подписчиков не вызывают
+	if len(notified) != 3 || !notified[0].Equal(target) || !notified[1].Equal(target.Add(time.Hour)) {
+		t.Errorf("notified %v, want Set, Advance and Reset", notified)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"hetzner-api-emulator/conformance"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	flag.Parse()
+
+	// Логи запросов только мешают читать отчёт
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard
+	log.SetOutput(io.Discard)
+
+	results, err := conformance.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conformance: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed, skipped := 0, 0
+	for _, result := range results {
+		// Эталон без подтверждения у Robot: расхождение показываем, но проверку не проваливаем
+		if result.EmulatorOnly {
+			skipped++
+			fmt.Printf("skip %s (emulator-only golden)\n", result.Scenario.Name)
+			if len(result.Diffs) > 0 {
+				fmt.Printf("\t%s\n", conformance.FormatDiffs(result.Diffs))
+			}
+			continue
+		}
+		if len(result.Diffs) == 0 {
+			fmt.Printf("ok   %s\n", result.Scenario.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL %s %s %s\n\t%s\n", result.Scenario.Name, result.Scenario.Method, result.Scenario.Path, conformance.FormatDiffs(result.Diffs))
+		if result.Source != "" {
+			fmt.Printf("\tgolden: %s\n", result.Source)
+		}
+	}
+
+	// Клиент должен покрывать все маршруты Robot, которые регистрирует эмулятор
+	routeDiffs, err := conformance.RouteDiffs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conformance: %v\n", err)
+		os.Exit(1)
+	}
+	if len(routeDiffs) == 0 {
+		fmt.Println("ok   client_routes")
+	} else {
+		failed++
+		fmt.Printf("FAIL client_routes\n\t%s\n", conformance.FormatDiffs(routeDiffs))
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed, %d skipped\n", failed, len(results)+1-skipped, skipped)
+		os.Exit(1)
+	}
+}
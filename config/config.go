@@ -1,38 +1,413 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"hetzner-api-emulator/lifecycle"
+	"hetzner-api-emulator/logger"
+	"hetzner-api-emulator/middlewares"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
 )
 
+// DatabaseConfig параметры подключения к базе данных
+type DatabaseConfig struct {
+	Connection string // mysql, postgres или sqlite
+	Host       string
+	Port       string
+	User       string
+	Password   string
+	Name       string // Имя базы; для sqlite — путь к файлу или :memory:
+}
+
+// IsInMemory сообщает, что база данных живёт только в памяти процесса и её нужно мигрировать при старте
+func (d DatabaseConfig) IsInMemory() bool {
+	if d.Connection != "sqlite" {
+		return false
+	}
+	return d.Name == "" || d.Name == ":memory:" || strings.Contains(d.Name, "mode=memory")
+}
+
+// Config конфигурация приложения после разбора и проверки
 type Config struct {
-	DBType       string
-	DBHost       string
-	DBPort       string
-	DBUser       string
-	DBPassword   string
-	DBName       string
-	Host         string
-	Port         string
-}
-
-// LoadConfig загружает конфигурацию приложения из переменных окружения
-func LoadConfig() *Config {
-	return &Config{
-		DBType:       getEnv("DB_TYPE", "postgres"),     // Тип базы данных: "postgres" или "mysql"
-		DBHost:       getEnv("DB_HOST", "localhost"),   // Хост базы данных
-		DBPort:       getEnv("DB_PORT", "5432"),        // Порт базы данных
-		DBUser:       getEnv("DB_USER", "myuser"),      // Имя пользователя базы данных
-		DBPassword:   getEnv("DB_PASSWORD", "mypassword"), // Пароль пользователя базы данных
-		DBName:       getEnv("DB_NAME", "mydatabase"),  // Название базы данных
-		Host:         getEnv("HOST", "0.0.0.0"),        // Хост приложения
-		Port:         getEnv("PORT", "8080"),           // Порт приложения
-	}
-}
-
-// getEnv возвращает значение переменной окружения или значение по умолчанию
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return fallback
+	File              string // YAML-файл, из которого загружена конфигурация (пусто, если не задан)
+	Database          DatabaseConfig
+	Host              string
+	Port              string
+	AdminHost         string
+	AdminPort         string
+	AdminToken        string
+	TrustedProxies    []string // Адреса и сети прокси, которым доверяется X-Forwarded-For; пусто — никому
+	Mode              string   // emulate, record или replay
+	Upstream          string
+	Cassette          string
+	Fixtures          string
+	AutoMigrate       bool // serve применяет недостающие миграции при старте, а не только предупреждает
+	LifecycleMode     lifecycle.Mode
+	LifecycleInterval time.Duration
+	FaultsFile        string
+	RateLimits        []middlewares.RateLimit
+	JournalSize       int
+	StrictMode        bool
+	LogLevel          slog.Level
+	LogFormat         string
+	AuthCacheTTL      time.Duration
+	AuthCacheSize     int
+	AuthAudit         middlewares.AuditSettings
+	BcryptCost        int
+	LoginLockout      middlewares.LockoutSettings
+}
+
+// option описывает один параметр: ключ в YAML-файле, переменную окружения, флаг и разбор значения
+type option struct {
+	key     string   // Ключ в YAML-файле; переменная окружения — он же в верхнем регистре, флаг — через дефис
+	aliases []string // Устаревшие имена переменных окружения
+	def     string
+	usage   string
+	apply   func(c *Config, value string) error
+}
+
+func (o option) env() string  { return strings.ToUpper(o.key) }
+func (o option) flag() string { return strings.ReplaceAll(o.key, "_", "-") }
+
+var options = []option{
+	{key: "db_connection", aliases: []string{"DB_TYPE"}, def: "postgres", usage: "Database driver: postgres, mysql or sqlite",
+		apply: func(c *Config, v string) error {
+			switch v {
+			case "postgres", "mysql", "sqlite":
+				c.Database.Connection = v
+				return nil
+			}
+			return fmt.Errorf("unknown database driver %q, expected postgres, mysql or sqlite", v)
+		}},
+	{key: "db_host", def: "localhost", usage: "Database host", apply: setString(func(c *Config) *string { return &c.Database.Host })},
+	{key: "db_port", def: "", usage: "Database port; defaults to 5432 for postgres and 3306 for mysql", apply: setPort(func(c *Config) *string { return &c.Database.Port }, true)},
+	{key: "db_user", def: "", usage: "Database user", apply: setString(func(c *Config) *string { return &c.Database.User })},
+	{key: "db_password", def: "", usage: "Database password", apply: setString(func(c *Config) *string { return &c.Database.Password })},
+	{key: "db_name", def: "", usage: "Database name; for sqlite a file path or :memory: (default)", apply: setString(func(c *Config) *string { return &c.Database.Name })},
+	{key: "host", def: "0.0.0.0", usage: "Address to listen on", apply: setString(func(c *Config) *string { return &c.Host })},
+	{key: "port", def: "8080", usage: "Port to listen on", apply: setPort(func(c *Config) *string { return &c.Port }, false)},
+	{key: "admin_host", def: "127.0.0.1", usage: "Address of the admin API when admin_port is set", apply: setString(func(c *Config) *string { return &c.AdminHost })},
+	{key: "admin_port", def: "", usage: "Separate port for the admin API", apply: setPort(func(c *Config) *string { return &c.AdminPort }, true)},
+	{key: "admin_token", def: "", usage: "Token for the admin API on the main port", apply: setString(func(c *Config) *string { return &c.AdminToken })},
+	{key: "trusted_proxies", def: "", usage: "Proxy addresses or CIDR ranges, separated by commas, whose X-Forwarded-For is used as the client IP (default: none)",
+		apply: func(c *Config, v string) error {
+			c.TrustedProxies = nil
+			for _, item := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' }) {
+				if net.ParseIP(item) == nil {
+					if _, _, err := net.ParseCIDR(item); err != nil {
+						return fmt.Errorf("invalid proxy %q, expected an IP address or CIDR range", item)
+					}
+				}
+				c.TrustedProxies = append(c.TrustedProxies, item)
+			}
+			return nil
+		}},
+	{key: "mode", def: "emulate", usage: "Mode: emulate, record (proxy to -upstream and write -cassette) or replay (serve from -cassette)",
+		apply: func(c *Config, v string) error {
+			switch v {
+			case "emulate", "record", "replay":
+				c.Mode = v
+				return nil
+			}
+			return fmt.Errorf("unknown mode %q, expected emulate, record or replay", v)
+		}},
+	{key: "upstream", def: "https://robot-ws.your-server.de", usage: "Upstream Robot URL for -mode=record", apply: setString(func(c *Config) *string { return &c.Upstream })},
+	{key: "cassette", def: "cassette.jsonl", usage: "JSONL cassette file for -mode=record and -mode=replay", apply: setString(func(c *Config) *string { return &c.Cassette })},
+	{key: "auto_migrate", def: "false", usage: "Apply pending migrations at startup instead of requiring migrate up", apply: setBool(func(c *Config) *bool { return &c.AutoMigrate })},
+	{key: "fixtures", def: "", usage: "Load users and servers from a YAML or JSON fixtures file at startup", apply: setFile(func(c *Config) *string { return &c.Fixtures })},
+	{key: "lifecycle_mode", def: "delete", usage: "What happens to a server after its cancellation date: delete or mark",
+		apply: func(c *Config, v string) (err error) {
+			c.LifecycleMode, err = lifecycle.ParseMode(v)
+			return err
+		}},
+	{key: "lifecycle_interval", def: "1m", usage: "How often cancellation dates are checked", apply: setDuration(func(c *Config) *time.Duration { return &c.LifecycleInterval })},
+	{key: "faults_file", def: "", usage: "JSON file with fault injection rules", apply: setFile(func(c *Config) *string { return &c.FaultsFile })},
+	{key: "rate_limits", def: "off", usage: "Rate limits: off, default or server=200/3600,reset=50/3600",
+		apply: func(c *Config, v string) (err error) {
+			c.RateLimits, err = middlewares.ParseRateLimits(v)
+			return err
+		}},
+	{key: "journal_size", def: "10000", usage: "How many recent requests the request journal keeps", apply: setInt(func(c *Config) *int { return &c.JournalSize }, 0, 0)},
+	{key: "strict_mode", def: "false", usage: "Validate requests against the OpenAPI description", apply: setBool(func(c *Config) *bool { return &c.StrictMode })},
+	{key: "log_level", def: "info", usage: "Log level: debug, info, warn or error",
+		apply: func(c *Config, v string) (err error) {
+			c.LogLevel, err = logger.ParseLevel(v)
+			return err
+		}},
+	{key: "log_format", def: "text", usage: "Log format: text or json",
+		apply: func(c *Config, v string) error {
+			switch v {
+			case "text", "json":
+				c.LogFormat = v
+				return nil
+			}
+			return fmt.Errorf("unknown log format %q, expected text or json", v)
+		}},
+	{key: "auth_cache_ttl", def: "5m", usage: "How long a verified password is remembered; 0 disables the cache", apply: setDuration(func(c *Config) *time.Duration { return &c.AuthCacheTTL })},
+	{key: "auth_cache_size", def: "1000", usage: "How many users the credential cache remembers", apply: setInt(func(c *Config) *int { return &c.AuthCacheSize }, 0, 0)},
+	{key: "auth_log_success", def: "false", usage: "Record successful logins in the auth audit, not only failures", apply: setBool(func(c *Config) *bool { return &c.AuthAudit.LogSuccess })},
+	{key: "auth_events_max", def: "10000", usage: "How many recent auth audit events are kept; 0 keeps all", apply: setInt(func(c *Config) *int { return &c.AuthAudit.MaxEvents }, 0, 0)},
+	{key: "auth_events_max_age", def: "0", usage: "How long auth audit events are kept, e.g. 720h; 0 keeps them regardless of age", apply: setDuration(func(c *Config) *time.Duration { return &c.AuthAudit.MaxAge })},
+	{key: "bcrypt_cost", def: "10", usage: "bcrypt cost for new passwords", apply: setInt(func(c *Config) *int { return &c.BcryptCost }, bcrypt.MinCost, bcrypt.MaxCost)},
+	{key: "login_lockout", def: "off", usage: "IP lockout: off, default or failures/window/block, e.g. 3/600/600",
+		apply: func(c *Config, v string) (err error) {
+			c.LoginLockout, err = middlewares.ParseLockoutSettings(v)
+			return err
+		}},
+}
+
+// Loader флаги конфигурации, зарегистрированные в наборе флагов команды
+type Loader struct {
+	fs         *flag.FlagSet
+	configFlag *string
+}
+
+// Register регистрирует флаги конфигурации в fs, чтобы команда могла добавить к ним свои и разобрать их сама
+func Register(fs *flag.FlagSet) *Loader {
+	loader := &Loader{fs: fs, configFlag: fs.String("config", "", "YAML configuration file (also CONFIG_FILE)")}
+	for _, opt := range options {
+		fs.String(opt.flag(), opt.def, opt.usage+" (also "+opt.env()+")")
+	}
+	return loader
+}
+
+// Load регистрирует флаги в fs, разбирает args и собирает конфигурацию
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	loader := Register(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+// Load собирает конфигурацию из значений по умолчанию, YAML-файла, переменных окружения и уже разобранных флагов.
+// Каждый следующий источник перекрывает предыдущий. Файл задаётся флагом -config или переменной CONFIG_FILE.
+// Все ошибки возвращаются разом
+func (l *Loader) Load() (*Config, error) {
+	fs := l.fs
+
+	// Значения и их источники, чтобы в ошибке было видно, откуда взялось неверное значение
+	values := make(map[string]string, len(options))
+	sources := make(map[string]string, len(options))
+	for _, opt := range options {
+		values[opt.key] = opt.def
+		sources[opt.key] = "default"
+	}
+
+	file := *l.configFlag
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		fileValues, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileValues {
+			values[key] = value
+			sources[key] = fmt.Sprintf("%s in %s", key, file)
+		}
+	}
+
+	for _, opt := range options {
+		for _, name := range append([]string{opt.env()}, opt.aliases...) {
+			if value, exists := os.LookupEnv(name); exists {
+				values[opt.key] = value
+				sources[opt.key] = "environment variable " + name
+				break
+			}
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		for _, opt := range options {
+			if f.Name == opt.flag() {
+				values[opt.key] = f.Value.String()
+				sources[opt.key] = "flag -" + f.Name
+			}
+		}
+	})
+
+	cfg := &Config{File: file}
+	var errs []error
+	for _, opt := range options {
+		if err := opt.apply(cfg, strings.TrimSpace(values[opt.key])); err != nil {
+			errs = append(errs, fmt.Errorf("%s (from %s): %w", opt.key, sources[opt.key], err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Порт и имя базы, которые зависят от драйвера
+	switch {
+	case cfg.Database.Port != "":
+	case cfg.Database.Connection == "postgres":
+		cfg.Database.Port = "5432"
+	case cfg.Database.Connection == "mysql":
+		cfg.Database.Port = "3306"
+	}
+	if cfg.Database.Connection == "sqlite" && cfg.Database.Name == "" {
+		cfg.Database.Name = ":memory:"
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate проверяет сочетания параметров, которые нельзя проверить по отдельности
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Database.Connection != "sqlite" {
+		required := []struct{ key, value string }{
+			{"db_host", c.Database.Host},
+			{"db_user", c.Database.User},
+			{"db_name", c.Database.Name},
+		}
+		for _, field := range required {
+			if field.value == "" {
+				errs = append(errs, fmt.Errorf("%s is required for %s", field.key, c.Database.Connection))
+			}
+		}
+	}
+	if c.Mode == "record" && c.Upstream == "" {
+		errs = append(errs, errors.New("upstream is required for mode record"))
+	}
+	if c.Mode != "emulate" && c.Cassette == "" {
+		errs = append(errs, fmt.Errorf("cassette is required for mode %s", c.Mode))
+	}
+	if c.LifecycleInterval < 0 {
+		errs = append(errs, errors.New("lifecycle_interval must not be negative"))
+	}
+	if c.AuthAudit.MaxAge < 0 {
+		errs = append(errs, errors.New("auth_events_max_age must not be negative"))
+	}
+	if c.AdminPort != "" && c.AdminPort == c.Port {
+		errs = append(errs, fmt.Errorf("admin_port %s is already used by the main server", c.AdminPort))
+	}
+	return errors.Join(errs...)
+}
+
+// readFile читает плоский YAML-файл вида "ключ: значение"; неизвестные ключи считаются ошибкой
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var nodes map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(options))
+	for _, opt := range options {
+		known[opt.key] = true
+	}
+
+	values := make(map[string]string, len(nodes))
+	var errs []error
+	for key, node := range nodes {
+		switch {
+		case !known[key]:
+			errs = append(errs, fmt.Errorf("unknown key %q in %s (line %d)", key, path, node.Line))
+		case node.Kind != yaml.ScalarNode:
+			errs = append(errs, fmt.Errorf("%s in %s (line %d) must be a single value", key, path, node.Line))
+		default:
+			values[key] = node.Value
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return values, nil
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+// setPort проверяет номер порта; optional разрешает пустое значение
+func setPort(field func(c *Config) *string, optional bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		if value == "" && optional {
+			*field(c) = value
+			return nil
+		}
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %q, expected a number from 1 to 65535", value)
+		}
+		*field(c) = value
+		return nil
+	}
+}
+
+// setFile проверяет, что указанный файл существует
+func setFile(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		if value != "" {
+			if _, err := os.Stat(value); err != nil {
+				return fmt.Errorf("file %s is not readable: %w", value, errors.Unwrap(err))
+			}
+		}
+		*field(c) = value
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, expected true or false", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a value like 30s, 5m or 1h", value)
+		}
+		*field(c) = duration
+		return nil
+	}
+}
+
+// setInt разбирает целое число в пределах [min, max]; нулевые пределы означают «не меньше нуля»
+func setInt(field func(c *Config) *int, min, max int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		if min == 0 && max == 0 {
+			if number < 0 {
+				return fmt.Errorf("must not be negative, got %d", number)
+			}
+		} else if number < min || number > max {
+			return fmt.Errorf("must be between %d and %d, got %d", min, max, number)
+		}
+		*field(c) = number
+		return nil
+	}
 }
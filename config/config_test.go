@@ -0,0 +1,292 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/lifecycle"
+)
+
+// clearEnv убирает переменные окружения всех параметров, чтобы окружение теста не влияло на результат
+func clearEnv(t *testing.T) {
+	t.Helper()
+	names := []string{"CONFIG_FILE"}
+	for _, opt := range options {
+		names = append(names, opt.env())
+		names = append(names, opt.aliases...)
+	}
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args)
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	cfg, err := load(t, "-db-connection", "sqlite")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Database.Name != ":memory:" || !cfg.Database.IsInMemory() {
+		t.Errorf("sqlite database = %q, want :memory:", cfg.Database.Name)
+	}
+	if cfg.Host != "0.0.0.0" || cfg.Port != "8080" || cfg.Mode != "emulate" {
+		t.Errorf("listen = %s:%s mode %s", cfg.Host, cfg.Port, cfg.Mode)
+	}
+	if cfg.LifecycleMode != lifecycle.ModeDelete || cfg.LifecycleInterval != time.Minute {
+		t.Errorf("lifecycle = %s every %s", cfg.LifecycleMode, cfg.LifecycleInterval)
+	}
+	if cfg.AuthCacheTTL != 5*time.Minute || cfg.AuthCacheSize != 1000 || cfg.BcryptCost != 10 {
+		t.Errorf("auth cache %s/%d, bcrypt cost %d", cfg.AuthCacheTTL, cfg.AuthCacheSize, cfg.BcryptCost)
+	}
+	if cfg.AuthAudit.LogSuccess || cfg.AuthAudit.MaxEvents != 10000 || cfg.AuthAudit.MaxAge != 0 {
+		t.Errorf("auth audit = %+v", cfg.AuthAudit)
+	}
+	if cfg.RateLimits != nil || cfg.LoginLockout.MaxFailures != 0 || cfg.StrictMode {
+		t.Errorf("rate limits %v, lockout %+v, strict %v", cfg.RateLimits, cfg.LoginLockout, cfg.StrictMode)
+	}
+	if cfg.AutoMigrate || cfg.TrustedProxies != nil {
+		t.Errorf("auto_migrate %v, trusted proxies %v", cfg.AutoMigrate, cfg.TrustedProxies)
+	}
+}
+
+func TestLoadAutoMigrate(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("AUTO_MIGRATE", "true")
+	cfg, err := load(t, "-db-connection", "sqlite", "-db-name", "emulator.db")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.AutoMigrate {
+		t.Error("auto_migrate = false, want true from AUTO_MIGRATE")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{"file", "", nil, "1001"},
+		{"environment over file", "1002", nil, "1002"},
+		{"flag over environment", "1002", []string{"-port", "1003"}, "1003"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			file := writeFile(t, "db_connection: sqlite\nport: 1001\n")
+			if tt.env != "" {
+				t.Setenv("PORT", tt.env)
+			}
+			cfg, err := load(t, append([]string{"-config", file}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Port != tt.want {
+				t.Errorf("port = %s, want %s", cfg.Port, tt.want)
+			}
+			if cfg.File != file {
+				t.Errorf("File = %q, want %q", cfg.File, file)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "db_connection: sqlite\nlog_format: json\n"))
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.LogFormat != "json" {
+		t.Errorf("log_format = %q, want json", cfg.LogFormat)
+	}
+}
+
+func TestLoadEnvironmentAlias(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_TYPE", "sqlite")
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.Connection != "sqlite" {
+		t.Errorf("db_connection = %q, want sqlite from DB_TYPE", cfg.Database.Connection)
+	}
+}
+
+func TestLoadDatabasePort(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-db-connection", "postgres"}, "5432"},
+		{[]string{"-db-connection", "mysql"}, "3306"},
+		{[]string{"-db-connection", "mysql", "-db-port", "3307"}, "3307"},
+		{[]string{"-db-connection", "sqlite"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			clearEnv(t)
+			cfg, err := load(t, append(tt.args, "-db-user", "emu", "-db-name", "emu")...)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Database.Port != tt.want {
+				t.Errorf("db_port = %q, want %q", cfg.Database.Port, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		wants []string
+	}{
+		{
+			name:  "all value errors at once, with their sources",
+			env:   map[string]string{"PORT": "http"},
+			args:  []string{"-db-connection", "sqlite", "-log-level", "loud", "-rate-limits", "server=1"},
+			wants: []string{"port (from environment variable PORT)", "log_level (from flag -log-level)", "rate_limits"},
+		},
+		{
+			name:  "unknown driver",
+			args:  []string{"-db-connection", "oracle"},
+			wants: []string{`unknown database driver "oracle"`},
+		},
+		{
+			name:  "server database needs user and name",
+			args:  []string{"-db-connection", "postgres"},
+			wants: []string{"db_user is required for postgres", "db_name is required for postgres"},
+		},
+		{
+			name:  "admin port clashes with the main port",
+			args:  []string{"-db-connection", "sqlite", "-port", "9000", "-admin-port", "9000"},
+			wants: []string{"admin_port 9000 is already used"},
+		},
+		{
+			name:  "replay without cassette",
+			args:  []string{"-db-connection", "sqlite", "-mode", "replay", "-cassette", ""},
+			wants: []string{"cassette is required for mode replay"},
+		},
+		{
+			name:  "negative durations",
+			args:  []string{"-db-connection", "sqlite", "-lifecycle-interval", "-1m", "-auth-events-max-age", "-1h"},
+			wants: []string{"lifecycle_interval must not be negative", "auth_events_max_age must not be negative"},
+		},
+		{
+			name:  "missing fixtures file",
+			args:  []string{"-db-connection", "sqlite", "-fixtures", "/does/not/exist.yaml"},
+			wants: []string{"fixtures (from flag -fixtures): file /does/not/exist.yaml is not readable"},
+		},
+		{
+			name:  "bcrypt cost out of range",
+			args:  []string{"-db-connection", "sqlite", "-bcrypt-cost", "2"},
+			wants: []string{"bcrypt_cost", "must be between 4 and 31"},
+		},
+		{
+			name:  "unknown key in file",
+			file:  "db_connection: sqlite\nprot: 8080\n",
+			wants: []string{`unknown key "prot"`, "line 2"},
+		},
+		{
+			name:  "nested value in file",
+			file:  "db_connection: sqlite\nport:\n  value: 8080\n",
+			wants: []string{"port in", "must be a single value"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+
+			_, err := load(t, args...)
+			if err == nil {
+				t.Fatal("Load succeeded, want an error")
+			}
+			for _, want := range tt.wants {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDatabaseConfigIsInMemory(t *testing.T) {
+	tests := []struct {
+		db   DatabaseConfig
+		want bool
+	}{
+		{DatabaseConfig{Connection: "sqlite", Name: ":memory:"}, true},
+		{DatabaseConfig{Connection: "sqlite", Name: ""}, true},
+		{DatabaseConfig{Connection: "sqlite", Name: "file:emu?mode=memory&cache=shared"}, true},
+		{DatabaseConfig{Connection: "sqlite", Name: "emulator.db"}, false},
+		{DatabaseConfig{Connection: "postgres", Name: ":memory:"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.db.IsInMemory(); got != tt.want {
+			t.Errorf("%+v: IsInMemory = %v, want %v", tt.db, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterWithCommandFlags(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_CONNECTION", "sqlite")
+	t.Setenv("PORT", "1002")
+
+	// Команда добавляет свои флаги к флагам конфигурации и разбирает их сама, как export и seed
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	output := fs.String("o", "", "Output file")
+	loader := Register(fs)
+	if err := fs.Parse([]string{"-o", "state.yaml", "-db-name", "emulator.db"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if *output != "state.yaml" || cfg.Database.Connection != "sqlite" || cfg.Database.Name != "emulator.db" || cfg.Port != "1002" {
+		t.Errorf("-o %q, config %+v", *output, cfg)
+	}
+	if cfg.Database.IsInMemory() {
+		t.Error("a database file is reported as in memory")
+	}
+}
@@ -0,0 +1,247 @@
+// Package conformance проверяет, что ответы эмулятора совпадают по форме с ответами Robot:
+// имена полей, типы, nullability, формат дат и коды ошибок. Эталоны лежат в golden/*.json: тело ответа
+// из документации Robot или записанное у настоящего Robot и поле source, откуда оно взято. Ответы самого
+// эмулятора эталонами не становятся, иначе проверка сравнивала бы эмулятор с самим собой.
+// Эталоны, которые не из чего взять у Robot, помечены emulator_only: Check их пропускает, отчёт показывает отдельно.
+//
+// Из теста:
+//
+//	func TestRobotContract(t *testing.T) { conformance.Check(t) }
+//
+// Из командной строки: go run ./cmd/conformance.
+package conformance
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"hetzner-api-emulator/emulator"
+	"hetzner-api-emulator/fixtures"
+	"hetzner-api-emulator/models"
+)
+
+//go:embed golden/*.json
+var goldenFiles embed.FS
+
+// Golden эталон ответа: статус, код ошибки Robot (если есть) и форма тела
+type Golden struct {
+	Status    int         `json:"status"`
+	ErrorCode string      `json:"error_code,omitempty"`
+	Shape     interface{} `json:"shape"`
+}
+
+// goldenFile файл эталона: образец ответа Robot и его происхождение
+type goldenFile struct {
+	Source       string          `json:"source"`                  // Откуда взят образец: раздел документации Robot или запись ответа
+	EmulatorOnly bool            `json:"emulator_only,omitempty"` // Образец не подтверждён Robot; source тогда начинается с "emulator:"
+	Status       int             `json:"status"`
+	Body         json.RawMessage `json:"body,omitempty"` // Нет для ответов без тела
+}
+
+// Result результат одного сценария
+type Result struct {
+	Scenario     Scenario
+	Source       string  // Происхождение эталона
+	Expected     *Golden // nil, если эталона нет
+	EmulatorOnly bool    // Эталон описывает только эмулятор: расхождение с ним не расхождение с Robot
+	Actual       Golden
+	Diffs        []string
+}
+
+// TB часть testing.TB, нужная для Check
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Fixtures данные, на которых выполняются сценарии
+func Fixtures() *fixtures.Document {
+	return &fixtures.Document{
+		Users: []models.UserSpec{
+			{Username: "test", Password: "test"},
+			{Username: "other", Password: "other"},
+			{Username: "readonly", Password: "readonly", Scopes: []string{"server:read"}},
+		},
+		Servers: []models.ServerSpec{
+			{
+				ServerNumber: 321, Username: "test", ServerName: "server1",
+				ServerIP: "123.123.123.123", ServerIPv6Net: "2a01:4f8:111:4221::",
+				Product: "AX41", DC: "FSN1-DC14", PaidUntil: "2030-06-30", LinkedStoragebox: 12345,
+				IPs: []models.IPSpec{{IP: "123.123.123.123", Mask: "32"}, {IP: "123.123.124.0", Mask: "29"}},
+			},
+			{
+				ServerNumber: 421, Username: "test", ServerName: "server2",
+				ServerIP: "123.123.123.124", ServerIPv6Net: "2a01:4f8:111:4222::",
+				Product: "EX44", DC: "HEL1-DC2", PaidUntil: "2030-06-30",
+				IPs: []models.IPSpec{{IP: "123.123.123.124", Mask: "32"}},
+			},
+			{
+				ServerNumber: 521, Username: "test", ServerName: "server3",
+				ServerIP: "123.123.123.125", ServerIPv6Net: "2a01:4f8:111:4223::",
+				Product: "SX64", DC: "FSN1-DC18", PaidUntil: "2030-06-30",
+			},
+			{
+				// Сервер другого пользователя: для test его не существует
+				ServerNumber: 621, Username: "other", ServerName: "foreign",
+				ServerIP: "123.123.123.126", ServerIPv6Net: "2a01:4f8:111:4224::",
+				Product: "AX41", DC: "NBG1-DC3", PaidUntil: "2030-06-30",
+			},
+		},
+	}
+}
+
+// Run выполняет все сценарии на новом эмуляторе и сравнивает ответы с эталонами
+func Run() ([]Result, error) {
+	emu, err := emulator.New(emulator.Options{Fixtures: Fixtures()})
+	if err != nil {
+		return nil, err
+	}
+	defer emu.Close()
+
+	// Даты в ответах не зависят от дня запуска
+	emu.Clock.Freeze()
+	emu.Clock.Set(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	var results []Result
+	for _, scenario := range Catalog {
+		actual, err := execute(emu.URL, scenario)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", scenario.Name, err)
+		}
+
+		result := Result{Scenario: scenario, Actual: *actual}
+		expected, file, err := loadGolden(scenario.Name)
+		if err != nil {
+			result.Diffs = []string{err.Error()}
+		} else {
+			result.Source = file.Source
+			result.EmulatorOnly = file.EmulatorOnly
+			result.Expected = expected
+			result.Diffs = compareGolden(expected, actual)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Check выполняет сценарии и сообщает о расхождениях через t. Сценарии с эталонами emulator_only пропускаются
+func Check(t TB) {
+	t.Helper()
+	results, err := Run()
+	if err != nil {
+		t.Fatalf("conformance: %v", err)
+	}
+	for _, result := range results {
+		if len(result.Diffs) > 0 && !result.EmulatorOnly {
+			t.Errorf("%s %s %s:\n\t%s", result.Scenario.Name, result.Scenario.Method, result.Scenario.Path, FormatDiffs(result.Diffs))
+		}
+	}
+
+	routeDiffs, err := RouteDiffs()
+	if err != nil {
+		t.Fatalf("conformance: %v", err)
+	}
+	if len(routeDiffs) > 0 {
+		t.Errorf("client routes:\n\t%s", FormatDiffs(routeDiffs))
+	}
+}
+
+// execute выполняет запрос сценария и строит форму ответа
+func execute(baseURL string, scenario Scenario) (*Golden, error) {
+	var body io.Reader
+	if scenario.Form != nil {
+		body = strings.NewReader(scenario.Form.Encode())
+	}
+	req, err := http.NewRequest(scenario.Method, baseURL+scenario.Path, body)
+	if err != nil {
+		return nil, err
+	}
+	if scenario.Form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	username, password := scenario.Username, scenario.Password
+	if username == "" {
+		username = "test"
+	}
+	if password == "" {
+		password = "test"
+	}
+	if password != "-" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return describeResponse(resp.StatusCode, data)
+}
+
+// describeResponse строит форму ответа по статусу и телу
+func describeResponse(status int, data []byte) (*Golden, error) {
+	golden := &Golden{Status: status, Shape: TypeNull}
+	if len(data) == 0 {
+		return golden, nil
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("response is not JSON: %w", err)
+	}
+	golden.Shape = Shape(parsed)
+	if envelope, ok := parsed.(map[string]interface{}); ok {
+		if errorBody, ok := envelope["error"].(map[string]interface{}); ok {
+			golden.ErrorCode, _ = errorBody["code"].(string)
+		}
+	}
+	return golden, nil
+}
+
+// loadGolden читает встроенный эталон сценария и возвращает его форму и файл с происхождением
+func loadGolden(name string) (*Golden, *goldenFile, error) {
+	data, err := goldenFiles.ReadFile("golden/" + name + ".json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("golden file for %s not found", name)
+	}
+	var file goldenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("golden/%s.json: %w", name, err)
+	}
+	if file.Source == "" {
+		return nil, nil, fmt.Errorf("golden/%s.json: source is required", name)
+	}
+	// Пометка и источник должны совпадать, чтобы эталон эмулятора не выдавался за образец Robot
+	if file.EmulatorOnly != strings.HasPrefix(file.Source, "emulator:") {
+		return nil, nil, fmt.Errorf("golden/%s.json: emulator_only must be set exactly when source starts with \"emulator:\"", name)
+	}
+	golden, err := describeResponse(file.Status, file.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("golden/%s.json: %w", name, err)
+	}
+	return golden, &file, nil
+}
+
+// compareGolden сравнивает статус, код ошибки и форму тела
+func compareGolden(expected, actual *Golden) []string {
+	var diffs []string
+	if expected.Status != actual.Status {
+		diffs = append(diffs, fmt.Sprintf("status: expected %d, got %d", expected.Status, actual.Status))
+	}
+	if expected.ErrorCode != actual.ErrorCode {
+		diffs = append(diffs, fmt.Sprintf("error code: expected %q, got %q", expected.ErrorCode, actual.ErrorCode))
+	}
+	return append(diffs, Compare(expected.Shape, actual.Shape, "$")...)
+}
@@ -0,0 +1,36 @@
+package conformance_test
+
+import (
+	"strings"
+	"testing"
+
+	"hetzner-api-emulator/conformance"
+)
+
+func TestRobotContract(t *testing.T) {
+	conformance.Check(t)
+}
+
+// Check пропускает эталоны emulator_only, но в тестах самого эмулятора они по-прежнему ловят случайные изменения
+func TestEmulatorOnlyGoldens(t *testing.T) {
+	results, err := conformance.Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	skipped := 0
+	for _, result := range results {
+		if !result.EmulatorOnly {
+			continue
+		}
+		skipped++
+		if !strings.HasPrefix(result.Source, "emulator:") {
+			t.Errorf("%s: emulator-only golden with source %q", result.Scenario.Name, result.Source)
+		}
+		if len(result.Diffs) > 0 {
+			t.Errorf("%s:\n\t%s", result.Scenario.Name, conformance.FormatDiffs(result.Diffs))
+		}
+	}
+	if skipped == 0 {
+		t.Error("no emulator-only goldens")
+	}
+}
@@ -0,0 +1,53 @@
+package conformance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"hetzner-api-emulator/client"
+	"hetzner-api-emulator/emulator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteDiffs запускает эмулятор и сверяет его маршруты с клиентом
+func RouteDiffs() ([]string, error) {
+	emu, err := emulator.New(emulator.Options{})
+	if err != nil {
+		return nil, err
+	}
+	defer emu.Close()
+	return CheckRoutes(emu.Router), nil
+}
+
+// CheckRoutes сверяет маршруты Robot в роутере эмулятора (routes.RegisterAllRoutes) с маршрутами,
+// для которых есть методы в пакете client. Служебные маршруты /__admin и публичные проверки не учитываются
+func CheckRoutes(router *gin.Engine) []string {
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		if strings.HasPrefix(route.Path, "/__admin") || route.Path == "/healthz" || route.Path == "/readyz" {
+			continue
+		}
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	covered := map[string]bool{}
+	for _, route := range client.Routes {
+		covered[route.Method+" "+route.Path] = true
+	}
+
+	var diffs []string
+	for key := range registered {
+		if !covered[key] {
+			diffs = append(diffs, fmt.Sprintf("%s: registered in the emulator but missing in client", key))
+		}
+	}
+	for key := range covered {
+		if !registered[key] {
+			diffs = append(diffs, fmt.Sprintf("%s: in client but not registered in the emulator", key))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
@@ -0,0 +1,45 @@
+package conformance
+
+import "net/url"
+
+// Scenario запрос к эмулятору, ответ на который сравнивается с эталоном golden/<Name>.json.
+// Сценарии выполняются по порядку на одном эмуляторе и могут зависеть от предыдущих
+type Scenario struct {
+	Name     string
+	Method   string
+	Path     string
+	Form     url.Values
+	Username string // По умолчанию test
+	Password string // По умолчанию test; "-" — запрос без авторизации
+}
+
+// Catalog сценарии, покрывающие ServerResponse, ServerResponseShort, ответ об отмене и конверт ошибки
+var Catalog = []Scenario{
+	{Name: "list_servers", Method: "GET", Path: "/server"},
+	{Name: "get_server", Method: "GET", Path: "/server/321"},
+	{Name: "get_server_without_ips", Method: "GET", Path: "/server/521"},
+	{Name: "update_server_name", Method: "POST", Path: "/server/321", Form: url.Values{"server_name": {"renamed"}}},
+	{Name: "get_cancellation", Method: "GET", Path: "/server/421/cancellation"},
+	{Name: "post_cancellation", Method: "POST", Path: "/server/421/cancellation", Form: url.Values{
+		"cancellation_date":   {"2030-02-01"},
+		"cancellation_reason": {"Server too expensive"},
+	}},
+	{Name: "get_cancellation_cancelled", Method: "GET", Path: "/server/421/cancellation"},
+	{Name: "post_cancellation_already_cancelled", Method: "POST", Path: "/server/421/cancellation"},
+	{Name: "delete_cancellation", Method: "DELETE", Path: "/server/421/cancellation"},
+	{Name: "delete_cancellation_not_cancelled", Method: "DELETE", Path: "/server/421/cancellation"},
+	{Name: "post_cancellation_invalid_date", Method: "POST", Path: "/server/421/cancellation", Form: url.Values{"cancellation_date": {"01.02.2030"}}},
+	{Name: "post_cancellation_invalid_reason", Method: "POST", Path: "/server/421/cancellation", Form: url.Values{
+		"cancellation_reason": {"Because"},
+		"reserve_location":    {"maybe"},
+	}},
+	{Name: "update_server_name_missing", Method: "POST", Path: "/server/321"},
+	{Name: "error_server_not_found", Method: "GET", Path: "/server/999"},
+	{Name: "error_server_foreign", Method: "GET", Path: "/server/621"},
+	{Name: "error_server_foreign_update", Method: "POST", Path: "/server/621", Form: url.Values{"server_name": {"stolen"}}},
+	{Name: "error_server_foreign_cancellation", Method: "GET", Path: "/server/621/cancellation"},
+	{Name: "error_unauthorized", Method: "GET", Path: "/server", Password: "wrong"},
+	{Name: "error_auth_header_missing", Method: "GET", Path: "/server", Password: "-"},
+	{Name: "error_forbidden_scope", Method: "POST", Path: "/server/321", Form: url.Values{"server_name": {"readonly"}}, Username: "readonly", Password: "readonly"},
+	{Name: "error_route_not_found", Method: "GET", Path: "/does-not-exist"},
+}
@@ -0,0 +1,156 @@
+package conformance
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Типы значений в описании формы ответа
+const (
+	TypeNull    = "null"
+	TypeBoolean = "boolean"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeString  = "string"
+	TypeDate    = "date" // Строка yyyy-MM-dd, как даты в Robot
+	TypeArray   = "array"
+)
+
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// Shape заменяет значения разобранного JSON их типами: объекты остаются объектами с теми же полями,
+// массив описывается формой элементов (одинаковые формы схлопываются)
+func Shape(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case float64:
+		if v == float64(int64(v)) {
+			return TypeInteger
+		}
+		return TypeNumber
+	case string:
+		if datePattern.MatchString(v) {
+			return TypeDate
+		}
+		return TypeString
+	case []interface{}:
+		if len(v) == 0 {
+			return TypeArray
+		}
+		var items []interface{}
+		seen := map[string]bool{}
+		for _, item := range v {
+			shape := Shape(item)
+			key := fmt.Sprintf("%v", shape)
+			if !seen[key] {
+				seen[key] = true
+				items = append(items, shape)
+			}
+		}
+		return items
+	case map[string]interface{}:
+		shape := map[string]interface{}{}
+		for key, item := range v {
+			shape[key] = Shape(item)
+		}
+		return shape
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// Compare сравнивает ожидаемую и фактическую формы и возвращает расхождения с путями вида $.server.paid_until
+func Compare(expected, actual interface{}, path string) []string {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object, got %s", path, describe(actual))}
+		}
+		var diffs []string
+		for _, key := range sortedKeys(e) {
+			if _, ok := a[key]; !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing field", path, key))
+				continue
+			}
+			diffs = append(diffs, Compare(e[key], a[key], path+"."+key)...)
+		}
+		for _, key := range sortedKeys(a) {
+			if _, ok := e[key]; !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s: unexpected field", path, key))
+			}
+		}
+		return diffs
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			// Пустой массив не противоречит описанию элементов
+			if actual == TypeArray {
+				return nil
+			}
+			return []string{fmt.Sprintf("%s: expected array, got %s", path, describe(actual))}
+		}
+		// Каждый элемент должен совпасть хотя бы с одной из описанных форм
+		var diffs []string
+		for i, item := range a {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			var first []string
+			matched := false
+			for j, candidate := range e {
+				candidateDiffs := Compare(candidate, item, itemPath)
+				if len(candidateDiffs) == 0 {
+					matched = true
+					break
+				}
+				if j == 0 {
+					first = candidateDiffs
+				}
+			}
+			if !matched {
+				diffs = append(diffs, first...)
+			}
+		}
+		return diffs
+	default:
+		if expected == TypeArray {
+			if _, ok := actual.([]interface{}); ok {
+				return nil
+			}
+		}
+		if expected != actual {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, describe(expected), describe(actual))}
+		}
+		return nil
+	}
+}
+
+// describe коротко описывает форму для сообщения об ошибке
+func describe(shape interface{}) string {
+	switch shape.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%v", shape)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatDiffs собирает расхождения в одну строку для отчёта
+func FormatDiffs(diffs []string) string {
+	return strings.Join(diffs, "\n\t")
+}
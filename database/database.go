@@ -3,62 +3,95 @@ package database
 import (
 	"fmt"
 	"log"
-	"net/http"
-	"os"
+	"time"
 
-	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/config"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
-var db *gorm.DB
+// gormConfig настройки GORM для всех баз. Стандартный журнал GORM печатает SQL вместе со значениями,
+// например хешами паролей; здесь запросы пишутся без значений и через log, то есть через slog
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger: gormlogger.New(log.Default(), gormlogger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  gormlogger.Warn,
+			IgnoreRecordNotFoundError: true,
+			ParameterizedQueries:      true,
+		}),
+	}
+}
 
-func ConnectDatabase() {
-	dbConnection := os.Getenv("DB_CONNECTION")
-	var dsn string
+// Connect открывает базу данных по параметрам из конфигурации
+func Connect(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	var dialector gorm.Dialector
 
-	switch dbConnection {
+	switch cfg.Connection {
 	case "mysql":
-		dsn = fmt.Sprintf(
+		dsn := fmt.Sprintf(
 			"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			os.Getenv("DB_USER"),
-			os.Getenv("DB_PASSWORD"),
-			os.Getenv("DB_HOST"),
-			os.Getenv("DB_PORT"),
-			os.Getenv("DB_NAME"),
+			cfg.User,
+			cfg.Password,
+			cfg.Host,
+			cfg.Port,
+			cfg.Name,
 		)
 		dialector = mysql.Open(dsn)
 
 	case "postgres":
-		dsn = fmt.Sprintf(
+		dsn := fmt.Sprintf(
 			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-			os.Getenv("DB_HOST"),
-			os.Getenv("DB_USER"),
-			os.Getenv("DB_PASSWORD"),
-			os.Getenv("DB_NAME"),
-			os.Getenv("DB_PORT"),
+			cfg.Host,
+			cfg.User,
+			cfg.Password,
+			cfg.Name,
+			cfg.Port,
 		)
 		dialector = postgres.Open(dsn)
 
+	case "sqlite":
+		// Имя базы — путь к файлу или :memory: для базы в памяти
+		path := cfg.Name
+		if path == "" {
+			path = ":memory:"
+		}
+		db, err := OpenSQLite(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+		}
+		log.Printf("SQLite database %s opened successfully", path)
+		return db, nil
+
 	default:
-		middlewares.SetError(nil, "DB_UNSUPPORTED_TYPE", http.StatusInternalServerError)
-		panic("Invalid DB_CONNECTION configuration")
+		return nil, fmt.Errorf("unsupported database driver %q, expected postgres, mysql or sqlite", cfg.Connection)
 	}
 
-	var err error
-	db, err = gorm.Open(dialector, &gorm.Config{})
+	db, err := gorm.Open(dialector, gormConfig())
 	if err != nil {
-		middlewares.SetError(nil, "Failed to connect to database", http.StatusInternalServerError)
-		panic("Database connection error")
+		return nil, fmt.Errorf("failed to connect to %s database %s at %s:%s: %w", cfg.Connection, cfg.Name, cfg.Host, cfg.Port, err)
 	}
 
 	log.Println("Database connection established successfully")
+	return db, nil
 }
 
-// GetDB returns the active database connection
-func GetDB() *gorm.DB {
-	return db
+// OpenSQLite открывает базу SQLite по пути к файлу или в памяти (":memory:").
+// База в памяти живёт, пока открыто соединение, поэтому пул ограничен одним соединением
+func OpenSQLite(path string) (*gorm.DB, error) {
+	sqliteDB, err := gorm.Open(sqlite.Open(path), gormConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := sqliteDB.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return sqliteDB, nil
 }
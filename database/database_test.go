@@ -0,0 +1,71 @@
+package database
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hetzner-api-emulator/config"
+	"hetzner-api-emulator/models"
+)
+
+func TestFailedQueryLogOmitsValues(t *testing.T) {
+	var output bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&output)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	db, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	const hash = "$2a$10$T1onSecretHashThatMustNotBeLogged"
+	if err := db.Create(&models.User{Username: "test", Password: hash}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{Username: "test", Password: hash}).Error; err == nil {
+		t.Fatal("duplicate user inserted, want a constraint error")
+	}
+
+	if !strings.Contains(output.String(), "INSERT INTO") {
+		t.Fatalf("failed insert was not logged: %q", output.String())
+	}
+	if strings.Contains(output.String(), hash) || strings.Contains(output.String(), `"test"`) {
+		t.Errorf("log contains bound values: %q", output.String())
+	}
+}
+
+func TestConnectErrors(t *testing.T) {
+	// Неверная конфигурация даёт ошибку с причиной, а не панику
+	_, err := Connect(config.DatabaseConfig{Connection: "oracle"})
+	if err == nil || !strings.Contains(err.Error(), `unsupported database driver "oracle"`) {
+		t.Errorf("Connect(oracle) = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "missing", "emulator.db")
+	_, err = Connect(config.DatabaseConfig{Connection: "sqlite", Name: path})
+	if err == nil || !strings.Contains(err.Error(), "failed to open SQLite database "+path) {
+		t.Errorf("Connect(%s) = %v", path, err)
+	}
+}
+
+func TestConnectSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emulator.db")
+	db, err := Connect(config.DatabaseConfig{Connection: "sqlite", Name: path})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database file: %v", err)
+	}
+}
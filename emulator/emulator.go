@@ -0,0 +1,169 @@
+// Package emulator запускает изолированный эмулятор Robot API внутри процесса, например в go test:
+//
+//	emu, err := emulator.New(emulator.Options{})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer emu.Close()
+//	emu.AddUser("test", "secret")
+//	resp, err := http.Get(emu.URL + "/server")
+package emulator
+
+import (
+	"net/http/httptest"
+	"time"
+
+	"hetzner-api-emulator/clock"
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/fixtures"
+	"hetzner-api-emulator/lifecycle"
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+	"hetzner-api-emulator/openapi"
+	"hetzner-api-emulator/routes"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Options настройки встроенного эмулятора
+type Options struct {
+	// AdminToken защищает /__admin; если не задан, генерируется случайный (см. Emulator.AdminToken)
+	AdminToken string
+	// Fixtures загружаются сразу после создания базы
+	Fixtures *fixtures.Document
+	// LifecycleMode что делать с сервером после даты отмены (по умолчанию удалять)
+	LifecycleMode lifecycle.Mode
+	// LifecycleInterval период фоновой проверки дат отмены; ноль — только при перестановке часов
+	LifecycleInterval time.Duration
+	// RateLimits квоты запросов; по умолчанию ограничений нет (см. middlewares.DefaultRateLimits)
+	RateLimits []middlewares.RateLimit
+	// Strict включает проверку запросов по описанию OpenAPI (ошибки INVALID_INPUT)
+	Strict bool
+	// AuthCacheTTL время жизни кэша проверенных паролей; по умолчанию 5 минут, отрицательное значение отключает кэш
+	AuthCacheTTL time.Duration
+	// Lockout блокировка IP после неудачных входов; по умолчанию отключена
+	Lockout middlewares.LockoutSettings
+	// Audit журнал аудита аутентификации; по умолчанию записываются только отказы
+	Audit middlewares.AuditSettings
+}
+
+// Emulator работающий эмулятор с собственной базой в памяти
+type Emulator struct {
+	URL        string
+	AdminToken string // Токен /__admin для заголовка X-Admin-Token или Authorization: Bearer
+	DB         *gorm.DB
+	Clock      *clock.Virtual               // Виртуальные часы эмулятора: Freeze, Set, Advance
+	Faults     *middlewares.FaultInjector   // Правила внедрения сбоев
+	Limits     *middlewares.RateLimiter     // Квоты запросов
+	Journal    *middlewares.Journal         // Журнал запросов для проверок в тестах
+	AuthCache  *middlewares.CredentialCache // Кэш проверенных паролей (nil, если отключён)
+	Lockout    *middlewares.LoginLockout    // Блокировка IP после неудачных входов
+	Router     *gin.Engine
+	Server     *httptest.Server
+
+	worker *lifecycle.Worker
+}
+
+// New создаёт базу SQLite в памяти, мигрирует её и запускает HTTP-сервер на свободном порту
+func New(opts Options) (*Emulator, error) {
+	// Пустой режим означает удаление; опечатку лучше увидеть сразу, чем в журнале работающего теста
+	lifecycleMode, err := lifecycle.ParseMode(string(opts.LifecycleMode))
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrations.Up(db, 0); err != nil {
+		return nil, err
+	}
+
+	if opts.Fixtures != nil {
+		if err := fixtures.Load(db, opts.Fixtures); err != nil {
+			return nil, err
+		}
+	}
+
+	clk := clock.New()
+
+	// Переходы по времени применяются при каждой перестановке часов и, если задан LifecycleInterval, периодически
+	worker := lifecycle.NewWorker(db, clk, lifecycleMode, opts.LifecycleInterval)
+	worker.Attach(clk)
+	worker.Start()
+
+	faults := middlewares.NewFaultInjector()
+	limiter := middlewares.NewRateLimiter(clk, opts.RateLimits)
+	journal := middlewares.NewJournal(clk, 0)
+	lockout := middlewares.NewLoginLockout(clk, opts.Lockout)
+	audit := middlewares.NewAuthAudit(db, clk, opts.Audit)
+	env := &routes.Env{DB: db, DBType: "sqlite", Clock: clk, Faults: faults, Limits: limiter, Journal: journal, Audit: audit, Lockout: lockout}
+	if opts.AuthCacheTTL == 0 {
+		opts.AuthCacheTTL = 5 * time.Minute
+	}
+	if opts.AuthCacheTTL > 0 {
+		env.AuthCache = middlewares.NewCredentialCache(opts.AuthCacheTTL, 1000)
+	}
+	if opts.Strict {
+		validator, err := openapi.NewValidator()
+		if err != nil {
+			worker.Stop()
+			return nil, err
+		}
+		env.Strict = validator
+	}
+	// Открытый /__admin позволил бы любому процессу на машине менять пользователей, поэтому токен есть всегда
+	if opts.AdminToken == "" {
+		token, err := models.GeneratePassword()
+		if err != nil {
+			worker.Stop()
+			return nil, err
+		}
+		opts.AdminToken = token
+	}
+	router := routes.NewRouter(env)
+	routes.MountAdmin(router, env, opts.AdminToken)
+
+	server := httptest.NewServer(router)
+	return &Emulator{
+		URL:        server.URL,
+		AdminToken: opts.AdminToken,
+		DB:         db,
+		Clock:      clk,
+		Faults:     faults,
+		Limits:     limiter,
+		Journal:    journal,
+		AuthCache:  env.AuthCache,
+		Lockout:    lockout,
+		Router:     router,
+		Server:     server,
+		worker:     worker,
+	}, nil
+}
+
+// Close останавливает HTTP-сервер и закрывает базу
+func (e *Emulator) Close() {
+	e.worker.Stop()
+	e.Server.Close()
+	if sqlDB, err := e.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// AddUser создаёт (или обновляет) пользователя с паролем в открытом виде
+func (e *Emulator) AddUser(username, password string) (*models.User, error) {
+	return models.UpsertUser(e.DB, models.UserSpec{Username: username, Password: password})
+}
+
+// AddServer создаёт (или обновляет) сервер; владелец задаётся через UserID или Username
+func (e *Emulator) AddServer(spec models.ServerSpec) (*models.Server, error) {
+	return models.UpsertServer(e.DB, spec)
+}
+
+// Load загружает фикстуры в базу эмулятора
+func (e *Emulator) Load(doc *fixtures.Document) error {
+	return fixtures.Load(e.DB, doc)
+}
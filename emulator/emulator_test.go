@@ -0,0 +1,615 @@
+package emulator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/emulator"
+	"hetzner-api-emulator/fixtures"
+	"hetzner-api-emulator/lifecycle"
+	"hetzner-api-emulator/logger"
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/models"
+)
+
+func newEmulator(t *testing.T, opts emulator.Options) *emulator.Emulator {
+	t.Helper()
+	emu, err := emulator.New(opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(emu.Close)
+	return emu
+}
+
+func get(t *testing.T, url, username, password string) *http.Response {
+	t.Helper()
+	return getFrom(t, url, username, password, "")
+}
+
+// getFrom как get, но с подставленным заголовком X-Forwarded-For
+func getFrom(t *testing.T, url, username, password, forwardedFor string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// post отправляет форму form с учётными данными пользователя
+func post(t *testing.T, url, username, password, form string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(form))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// admin выполняет запрос к /__admin с токеном token и возвращает статус и тело
+func admin(t *testing.T, emu *emulator.Emulator, method, path, token, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, emu.URL+"/__admin"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Admin-Token", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestNewServesAuthenticatedRequests(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	if emu.URL == "" {
+		t.Fatal("URL is empty")
+	}
+	if _, err := emu.AddUser("test", "secret"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	if _, err := emu.AddServer(models.ServerSpec{ServerNumber: 321, Username: "test", Product: "AX41", DC: "FSN1-DC14"}); err != nil {
+		t.Fatalf("AddServer: %v", err)
+	}
+
+	resp := get(t, emu.URL+"/server/321", "test", "secret")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var body struct {
+		Server struct {
+			ServerNumber int    `json:"server_number"`
+			Product      string `json:"product"`
+			Traffic      string `json:"traffic"`
+		} `json:"server"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Server.ServerNumber != 321 || body.Server.Product != "AX41" || body.Server.Traffic != "unlimited" {
+		t.Errorf("server = %+v", body.Server)
+	}
+}
+
+func TestNewRejectsBadCredentials(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	if _, err := emu.AddUser("test", "secret"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+
+	for name, password := range map[string]string{"wrong password": "nope", "no credentials": ""} {
+		username := "test"
+		if password == "" {
+			username = ""
+		}
+		// Robot отвечает одним кодом при любой причине отказа
+		resp := get(t, emu.URL+"/server", username, password)
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(string(data), `"code":"UNAUTHORIZED"`) {
+			t.Errorf("%s: status = %d, body %s, want 401 UNAUTHORIZED", name, resp.StatusCode, data)
+		}
+	}
+}
+
+func TestAuthDoesNotLogCredentials(t *testing.T) {
+	// Журнал по умолчанию — буфер с уровнем debug; стандартный log после slog.SetDefault тоже пишет в него
+	var buf bytes.Buffer
+	previous, writer, flags := slog.Default(), log.Writer(), log.Flags()
+	bufLogger, err := logger.New(&buf, slog.LevelDebug, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.SetDefault(bufLogger)
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+
+	emu := newEmulator(t, emulator.Options{})
+	if _, err := emu.AddUser("test", "hunter2"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	get(t, emu.URL+"/server", "test", "hunter2")
+	get(t, emu.URL+"/server", "test", "wrong-hunter3")
+	get(t, emu.URL+"/server", "nobody", "hunter4")
+
+	out := buf.String()
+	if !strings.Contains(out, "authentication failed") {
+		t.Fatalf("no failed logins in the log:\n%s", out)
+	}
+	for _, secret := range []string{"hunter2", "hunter3", "hunter4", "Basic "} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q leaked into the log:\n%s", secret, out)
+		}
+	}
+}
+
+func TestNewLifecycleMode(t *testing.T) {
+	for _, mode := range []lifecycle.Mode{"", lifecycle.ModeDelete, lifecycle.ModeMark} {
+		emu, err := emulator.New(emulator.Options{LifecycleMode: mode})
+		if err != nil {
+			t.Errorf("mode %q: %v", mode, err)
+			continue
+		}
+		emu.Close()
+	}
+
+	if _, err := emulator.New(emulator.Options{LifecycleMode: "remove"}); err == nil {
+		t.Error("unknown lifecycle mode accepted")
+	}
+}
+
+func TestCancellationExpiresAfterUTCDate(t *testing.T) {
+	// Часовой пояс процесса не должен сдвигать дату отмены и момент снятия сервера
+	local := time.Local
+	time.Local = time.FixedZone("UTC-10", -10*3600)
+	t.Cleanup(func() { time.Local = local })
+
+	emu := newEmulator(t, emulator.Options{})
+	if _, err := emu.AddUser("test", "secret"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	if _, err := emu.AddServer(models.ServerSpec{ServerNumber: 321, Username: "test", Product: "AX41", DC: "FSN1-DC14"}); err != nil {
+		t.Fatalf("AddServer: %v", err)
+	}
+	// Часы идут, как в работающем эмуляторе: Now отдаёт время в поясе процесса
+	emu.Clock.Set(time.Date(2030, 1, 1, 5, 0, 0, 0, time.UTC))
+
+	resp := post(t, emu.URL+"/server/321/cancellation", "test", "secret", "")
+	var body struct {
+		Cancellation struct {
+			CancellationDate string `json:"cancellation_date"`
+		} `json:"cancellation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("cancel: status = %d, decode %v", resp.StatusCode, err)
+	}
+	if body.Cancellation.CancellationDate != "2030-01-08" {
+		t.Errorf("cancellation_date = %q, want 2030-01-08", body.Cancellation.CancellationDate)
+	}
+
+	emu.Clock.Set(time.Date(2030, 1, 8, 23, 0, 0, 0, time.UTC))
+	if resp := get(t, emu.URL+"/server/321", "test", "secret"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("on the cancellation date: status = %d, want 200", resp.StatusCode)
+	}
+	emu.Clock.Advance(2 * time.Hour)
+	if resp := get(t, emu.URL+"/server/321", "test", "secret"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("after the cancellation date: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestCancellationChecksOwnershipFirst(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	for _, username := range []string{"test", "other"} {
+		if _, err := emu.AddUser(username, "secret"); err != nil {
+			t.Fatalf("AddUser: %v", err)
+		}
+	}
+	for number, owner := range map[int]string{321: "test", 621: "other"} {
+		if _, err := emu.AddServer(models.ServerSpec{ServerNumber: number, Username: owner, Product: "AX41", DC: "FSN1-DC14"}); err != nil {
+			t.Fatalf("AddServer: %v", err)
+		}
+	}
+
+	// Неверные параметры для чужого и несуществующего сервера дают тот же 404, что и верные
+	tests := []struct {
+		name, path, form string
+		wantStatus       int
+		wantCode         string
+	}{
+		{"foreign server, bad date", "/server/621/cancellation", "cancellation_date=31.01.2030", http.StatusNotFound, "SERVER_NOT_FOUND"},
+		{"foreign server, bad reason", "/server/621/cancellation", "cancellation_reason=Bored", http.StatusNotFound, "SERVER_NOT_FOUND"},
+		{"foreign server, valid input", "/server/621/cancellation", "cancellation_date=2030-12-31", http.StatusNotFound, "SERVER_NOT_FOUND"},
+		{"missing server, bad date", "/server/999/cancellation", "cancellation_date=31.01.2030", http.StatusNotFound, "SERVER_NOT_FOUND"},
+		{"own server, bad date", "/server/321/cancellation", "cancellation_date=31.01.2030", http.StatusBadRequest, "INVALID_INPUT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post(t, emu.URL+tt.path, "test", "secret", tt.form)
+			data, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(string(data), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("status = %d, body %s, want %d %s", resp.StatusCode, data, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAdminClock(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	type clockState struct {
+		Clock struct {
+			Now    string `json:"now"`
+			Frozen bool   `json:"frozen"`
+		} `json:"clock"`
+	}
+	call := func(method, path string) clockState {
+		t.Helper()
+		status, body := admin(t, emu, method, path, emu.AdminToken, "")
+		if status != http.StatusOK {
+			t.Fatalf("%s %s: status = %d, body %s", method, path, status, body)
+		}
+		var state clockState
+		if err := json.Unmarshal([]byte(body), &state); err != nil {
+			t.Fatalf("decode %s: %v", body, err)
+		}
+		return state
+	}
+
+	if state := call(http.MethodPost, "/clock/freeze"); !state.Clock.Frozen {
+		t.Error("freeze: clock is running")
+	}
+	if state := call(http.MethodPost, "/clock/set?time=2030-01-31"); state.Clock.Now != "2030-01-31T00:00:00Z" {
+		t.Errorf("set: now = %s", state.Clock.Now)
+	}
+	if state := call(http.MethodPost, "/clock/advance?days=1&duration=90m"); state.Clock.Now != "2030-02-01T01:30:00Z" {
+		t.Errorf("advance: now = %s", state.Clock.Now)
+	}
+	if state := call(http.MethodGet, "/clock"); state.Clock.Now != "2030-02-01T01:30:00Z" || !state.Clock.Frozen {
+		t.Errorf("get: %+v", state.Clock)
+	}
+	if now := emu.Clock.Now().UTC(); !now.Equal(time.Date(2030, 2, 1, 1, 30, 0, 0, time.UTC)) {
+		t.Errorf("emulator clock = %s", now)
+	}
+	if state := call(http.MethodPost, "/clock/unfreeze"); state.Clock.Frozen {
+		t.Error("unfreeze: clock is frozen")
+	}
+	if state := call(http.MethodPost, "/clock/reset"); strings.HasPrefix(state.Clock.Now, "2030") || state.Clock.Frozen {
+		t.Errorf("reset: %+v, want real time", state.Clock)
+	}
+
+	for _, path := range []string{"/clock/set?time=tomorrow", "/clock/advance", "/clock/advance?duration=-1h", "/clock/advance?days=x"} {
+		if status, body := admin(t, emu, http.MethodPost, path, emu.AdminToken, ""); status != http.StatusBadRequest || !strings.Contains(body, "INVALID_INPUT") {
+			t.Errorf("%s: status = %d, body %s, want 400 INVALID_INPUT", path, status, body)
+		}
+	}
+}
+
+func TestCloseStopsServer(t *testing.T) {
+	emu, err := emulator.New(emulator.Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	url := emu.URL
+	emu.Close()
+
+	if resp, err := http.Get(url + "/healthz"); err == nil {
+		resp.Body.Close()
+		t.Fatal("server still answers after Close")
+	}
+}
+
+func TestAdminAPIRequiresToken(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	if emu.AdminToken == "" {
+		t.Fatal("no admin token generated")
+	}
+	if status, _ := admin(t, emu, http.MethodGet, "/state", "", ""); status != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want 401", status)
+	}
+	if status, _ := admin(t, emu, http.MethodGet, "/state", "guess", ""); status != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", status)
+	}
+	if status, _ := admin(t, emu, http.MethodGet, "/state", emu.AdminToken, ""); status != http.StatusOK {
+		t.Errorf("with token: status = %d, want 200", status)
+	}
+
+	other := newEmulator(t, emulator.Options{})
+	if other.AdminToken == emu.AdminToken {
+		t.Error("two emulators share an admin token")
+	}
+	custom := newEmulator(t, emulator.Options{AdminToken: "secret"})
+	if custom.AdminToken != "secret" {
+		t.Errorf("AdminToken = %q, want the configured one", custom.AdminToken)
+	}
+}
+
+func TestAdminRequests(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	if _, err := emu.AddUser("test", "secret"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	get(t, emu.URL+"/server", "test", "secret")
+	get(t, emu.URL+"/server/999", "test", "secret")
+
+	var journal struct {
+		Count    int                        `json:"count"`
+		Requests []middlewares.JournalEntry `json:"requests"`
+	}
+	status, body := admin(t, emu, http.MethodGet, "/requests?path=/server/*&status=404", emu.AdminToken, "")
+	if err := json.Unmarshal([]byte(body), &journal); err != nil || status != http.StatusOK {
+		t.Fatalf("status = %d, body %s", status, body)
+	}
+	if journal.Count != 1 || journal.Requests[0].Path != "/server/999" || journal.Requests[0].UserID == 0 {
+		t.Errorf("journal = %+v, want the request for /server/999", journal)
+	}
+
+	for _, query := range []string{"status=abc", "user_id=abc", "since=yesterday"} {
+		if status, body := admin(t, emu, http.MethodGet, "/requests?"+query, emu.AdminToken, ""); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, body %s, want 400", query, status, body)
+		}
+	}
+
+	if status, _ := admin(t, emu, http.MethodDelete, "/requests", emu.AdminToken, ""); status != http.StatusNoContent {
+		t.Errorf("clear: status = %d, want 204", status)
+	}
+	if entries := emu.Journal.Entries(middlewares.JournalFilter{}); len(entries) != 0 {
+		t.Errorf("after clear: %d entries", len(entries))
+	}
+}
+
+func TestAdminUserConflicts(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	for _, username := range []string{"test", "other"} {
+		if status, body := admin(t, emu, http.MethodPost, "/users", emu.AdminToken, `{"username":"`+username+`","password":"secret"}`); status != http.StatusCreated {
+			t.Fatalf("create %s: status = %d, body %s", username, status, body)
+		}
+	}
+
+	tests := []struct {
+		name, method, path, body string
+	}{
+		{"create with a taken username", http.MethodPost, "/users", `{"username":"test","password":"secret"}`},
+		{"rename to a taken username", http.MethodPut, "/users/2", `{"username":"test"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := admin(t, emu, tt.method, tt.path, emu.AdminToken, tt.body)
+			if status != http.StatusConflict || !strings.Contains(body, "USER_ALREADY_EXISTS") {
+				t.Errorf("status = %d, body %s, want 409 USER_ALREADY_EXISTS", status, body)
+			}
+			// Текст ошибки драйвера клиенту не отдаётся
+			if strings.Contains(strings.ToLower(body), "constraint") || strings.Contains(body, "users.username") {
+				t.Errorf("driver error leaked: %s", body)
+			}
+		})
+	}
+}
+
+func TestAdminUserLifecycle(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	user, err := emu.AddUser("test", "secret")
+	if err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	_, err = emu.AddServer(models.ServerSpec{ServerNumber: 321, Username: "test", Product: "AX41", DC: "FSN1-DC14",
+		IPs: []models.IPSpec{{IP: "203.0.113.10", Mask: "32"}}})
+	if err != nil {
+		t.Fatalf("AddServer: %v", err)
+	}
+	path := "/users/" + strconv.Itoa(user.ID)
+	status := func(t *testing.T, password string) int {
+		t.Helper()
+		return get(t, emu.URL+"/server", "test", password).StatusCode
+	}
+	call := func(t *testing.T, method, target, body string, want int) string {
+		t.Helper()
+		code, resp := admin(t, emu, method, target, emu.AdminToken, body)
+		if code != want {
+			t.Fatalf("%s %s: status = %d, body %s, want %d", method, target, code, resp, want)
+		}
+		return resp
+	}
+
+	// Второй запрос проходит по кэшу проверенных паролей
+	if status(t, "secret") != http.StatusOK || status(t, "secret") != http.StatusOK || emu.AuthCache.Stats().Hits == 0 {
+		t.Fatalf("the password is not cached: %+v", emu.AuthCache.Stats())
+	}
+
+	t.Run("disable and enable", func(t *testing.T) {
+		if resp := call(t, http.MethodPost, path+"/disable", "", http.StatusOK); !strings.Contains(resp, `"disabled":true`) {
+			t.Errorf("disable = %s", resp)
+		}
+		if code := status(t, "secret"); code != http.StatusUnauthorized {
+			t.Errorf("disabled user: status = %d, want 401 despite the cached password", code)
+		}
+		call(t, http.MethodPost, path+"/enable", "", http.StatusOK)
+		if code := status(t, "secret"); code != http.StatusOK {
+			t.Errorf("enabled user: status = %d, want 200", code)
+		}
+	})
+
+	t.Run("password reset", func(t *testing.T) {
+		if resp := call(t, http.MethodPost, path+"/rotate-password", `{"password":"changed"}`, http.StatusOK); strings.Contains(resp, "changed") {
+			t.Errorf("a given password is echoed: %s", resp)
+		}
+		// Старый пароль в кэше больше не совпадает: хеш в базе сменился
+		if code := status(t, "secret"); code != http.StatusUnauthorized {
+			t.Errorf("old password: status = %d, want 401", code)
+		}
+		if code := status(t, "changed"); code != http.StatusOK {
+			t.Errorf("new password: status = %d, want 200", code)
+		}
+
+		var generated models.UserSpec
+		if err := json.Unmarshal([]byte(call(t, http.MethodPost, path+"/rotate-password", "", http.StatusOK)), &generated); err != nil || generated.Password == "" {
+			t.Fatalf("generated password: %+v, %v", generated, err)
+		}
+		if status(t, "changed") != http.StatusUnauthorized || status(t, generated.Password) != http.StatusOK {
+			t.Error("the generated password did not replace the previous one")
+		}
+		call(t, http.MethodPost, path+"/rotate-password", `{"password":"secret"}`, http.StatusOK)
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		call(t, http.MethodPut, path+"/scopes", `{"scopes":["reset:read"]}`, http.StatusOK)
+		resp := get(t, emu.URL+"/server", "test", "secret")
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(data), "FORBIDDEN") {
+			t.Errorf("without server:read: status = %d, body %s, want 403 FORBIDDEN", resp.StatusCode, data)
+		}
+		call(t, http.MethodPut, path+"/scopes", `{"scopes":["server:read"]}`, http.StatusOK)
+		if code := status(t, "secret"); code != http.StatusOK {
+			t.Errorf("with server:read: status = %d, want 200", code)
+		}
+		call(t, http.MethodPut, path+"/scopes", `{"scopes":["server"]}`, http.StatusBadRequest)
+		if resp := call(t, http.MethodPut, path+"/scopes", `{"scopes":[]}`, http.StatusOK); !strings.Contains(resp, `"scopes":[]`) {
+			t.Errorf("clear scopes = %s", resp)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		call(t, http.MethodDelete, path, "", http.StatusNoContent)
+		if resp := call(t, http.MethodGet, path, "", http.StatusNotFound); !strings.Contains(resp, "USER_NOT_FOUND") {
+			t.Errorf("deleted user = %s", resp)
+		}
+		if code := status(t, "secret"); code != http.StatusUnauthorized {
+			t.Errorf("deleted user: status = %d, want 401", code)
+		}
+		// Серверы и IP-адреса удаляются вместе с пользователем
+		call(t, http.MethodGet, "/servers/321", "", http.StatusNotFound)
+		if resp := call(t, http.MethodGet, "/ips", "", http.StatusOK); strings.Contains(resp, "203.0.113.10") {
+			t.Errorf("IP of a deleted user's server left: %s", resp)
+		}
+		call(t, http.MethodDelete, path, "", http.StatusNotFound)
+		call(t, http.MethodPost, "/users/abc/disable", "", http.StatusBadRequest)
+	})
+}
+
+func TestAdminServerErrors(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	if _, err := emu.AddUser("test", "secret"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	for _, body := range []string{
+		`{"server_number":1,"username":"test","product":"DS 3000","dc":"NBG1-DC1","ips":[{"id":100,"ip":"203.0.113.1","mask":"32"}]}`,
+		`{"server_number":2,"username":"test","product":"DS 3000","dc":"NBG1-DC1","ips":[{"id":200,"ip":"203.0.113.2","mask":"32"}]}`,
+	} {
+		if status, resp := admin(t, emu, http.MethodPost, "/servers", emu.AdminToken, body); status != http.StatusCreated {
+			t.Fatalf("create server: status = %d, body %s", status, resp)
+		}
+	}
+
+	tests := []struct {
+		name, method, path, body string
+		wantStatus               int
+		wantBody                 string
+	}{
+		{"taken server number", http.MethodPost, "/servers",
+			`{"server_number":1,"username":"test","product":"DS 3000","dc":"NBG1-DC1"}`, http.StatusConflict, "SERVER_ALREADY_EXISTS"},
+		{"create with a taken IP id", http.MethodPost, "/servers",
+			`{"server_number":3,"username":"test","product":"DS 3000","dc":"NBG1-DC1","ips":[{"id":100,"ip":"203.0.113.3","mask":"32"}]}`, http.StatusConflict, "CONFLICT"},
+		{"update with a taken IP id", http.MethodPut, "/servers/1",
+			`{"product":"DS 3000","dc":"NBG1-DC1","ips":[{"id":200,"ip":"203.0.113.3","mask":"32"}]}`, http.StatusConflict, "CONFLICT"},
+		{"unknown product", http.MethodPost, "/servers",
+			`{"server_number":3,"username":"test","product":"DS 9000","dc":"NBG1-DC1"}`, http.StatusBadRequest, "unknown product"},
+		{"unknown user", http.MethodPut, "/servers/1",
+			`{"username":"nobody","product":"DS 3000","dc":"NBG1-DC1"}`, http.StatusBadRequest, `user \"nobody\" not found`},
+		{"invalid date", http.MethodPut, "/servers/1",
+			`{"product":"DS 3000","dc":"NBG1-DC1","paid_until":"tomorrow"}`, http.StatusBadRequest, "invalid paid_until"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := admin(t, emu, tt.method, tt.path, emu.AdminToken, tt.body)
+			if status != tt.wantStatus || !strings.Contains(body, tt.wantBody) {
+				t.Errorf("status = %d, body %s, want %d %s", status, body, tt.wantStatus, tt.wantBody)
+			}
+			if strings.Contains(strings.ToLower(body), "constraint") || strings.Contains(body, "ips.id") {
+				t.Errorf("driver error leaked: %s", body)
+			}
+		})
+	}
+
+	// Неудачная замена не трогает IP-адреса сервера
+	status, body := admin(t, emu, http.MethodPut, "/ips/100", emu.AdminToken, `{"mask":"29"}`)
+	if status != http.StatusOK || !strings.Contains(body, `"server_number":1`) || !strings.Contains(body, `"mask":"29"`) {
+		t.Errorf("update IP: status = %d, body %s", status, body)
+	}
+}
+
+func TestAllowedIPsIgnoreSpoofedForwardedFor(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{})
+	err := emu.Load(&fixtures.Document{Users: []models.UserSpec{
+		{Username: "office", Password: "secret", AllowedIPs: []string{"203.0.113.0/24"}},
+	}})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name, password, forwardedFor string
+		want                         int
+	}{
+		{"spoofed allowed address", "secret", "203.0.113.5", http.StatusForbidden},
+		{"no header", "secret", "", http.StatusForbidden},
+		{"wrong password is checked before the allowlist", "guess", "", http.StatusUnauthorized},
+		{"wrong password with a spoofed address", "guess", "203.0.113.5", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := getFrom(t, emu.URL+"/server", "office", tt.password, tt.forwardedFor); resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockoutIgnoresSpoofedForwardedFor(t *testing.T) {
+	emu := newEmulator(t, emulator.Options{Lockout: middlewares.LockoutSettings{MaxFailures: 3, Window: 600, BlockFor: 600}})
+	if _, err := emu.AddUser("test", "secret"); err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+
+	// Каждая попытка притворяется новым адресом, но считается по адресу соединения
+	for i, forwardedFor := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		if resp := getFrom(t, emu.URL+"/server", "test", "guess", forwardedFor); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status = %d, want 401", i+1, resp.StatusCode)
+		}
+	}
+	if resp := getFrom(t, emu.URL+"/server", "test", "secret", "198.51.100.4"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("after 3 failures: status = %d, want 403 IP_BLOCKED", resp.StatusCode)
+	}
+}
@@ -0,0 +1,148 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hetzner-api-emulator/models"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// Document описывает файл фикстур. Подсети задаются как IP-адреса сервера с маской.
+// Других ресурсов (SSH-ключей, storage box, vSwitch) в эмуляторе нет, поэтому нет их и в фикстурах:
+// linked_storagebox — только поле сервера.
+//
+//	users:
+//	  - username: test
+//	    password: secret
+//	servers:
+//	  - server_number: 321
+//	    username: test
+//	    server_name: server1
+//	    product: AX41
+//	    dc: FSN1-DC14
+//	    ips:
+//	      - ip: 123.123.123.123
+//	        mask: "32"
+type Document struct {
+	Users   []models.UserSpec   `json:"users" yaml:"users"`
+	Servers []models.ServerSpec `json:"servers" yaml:"servers"`
+}
+
+// Parse разбирает фикстуры в формате JSON (.json) или YAML (.yaml, .yml)
+func Parse(path string, data []byte) (*Document, error) {
+	var doc Document
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	default:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	return &doc, nil
+}
+
+// LoadFile читает файл фикстур и загружает его в базу данных
+func LoadFile(db *gorm.DB, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	doc, err := Parse(path, data)
+	if err != nil {
+		return err
+	}
+	return Load(db, doc)
+}
+
+// Load идемпотентно загружает фикстуры: пользователи обновляются по Username, серверы по ServerNumber
+func Load(db *gorm.DB, doc *Document) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, user := range doc.Users {
+			if _, err := models.UpsertUser(tx, user); err != nil {
+				return fmt.Errorf("user %q: %w", user.Username, err)
+			}
+		}
+		for _, server := range doc.Servers {
+			if _, err := models.UpsertServer(tx, server); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Replace заменяет пользователей, серверы и IP-адреса содержимым документа. Журнал аудита не трогается
+func Replace(db *gorm.DB, doc *Document) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.IP{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("1 = 1").Delete(&models.Server{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&models.User{}).Error; err != nil {
+			return err
+		}
+		return Load(tx, doc)
+	})
+}
+
+// Export выгружает пользователей и действующие серверы в документ фикстур, который снова загружается через Load.
+// Пароли выгружаются bcrypt-хешами. Идентификаторы не выгружаются: серверы привязаны к пользователям по имени
+func Export(db *gorm.DB) (*Document, error) {
+	var users []models.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var servers []models.Server
+	if err := db.Preload("IPs").Order("server_number").Find(&servers).Error; err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Users: []models.UserSpec{}, Servers: []models.ServerSpec{}}
+	usernames := make(map[int]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+		spec := models.NewUserSpec(user)
+		spec.ID = 0
+		spec.CreatedAt = nil
+		spec.PasswordHash = user.Password
+		doc.Users = append(doc.Users, spec)
+	}
+	for _, server := range servers {
+		spec := models.NewServerSpec(server)
+		spec.UserID = 0
+		spec.Username = usernames[server.UserID]
+		for i := range spec.IPs {
+			spec.IPs[i].ID = 0
+			spec.IPs[i].ServerNumber = 0
+		}
+		doc.Servers = append(doc.Servers, spec)
+	}
+	return doc, nil
+}
+
+// Marshal сериализует документ в JSON (format "json") или YAML (format "yaml")
+func Marshal(doc *Document, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "yaml":
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected yaml or json", format)
+	}
+}
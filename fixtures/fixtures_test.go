@@ -0,0 +1,147 @@
+package fixtures_test
+
+import (
+	"reflect"
+	"testing"
+
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/fixtures"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := models.SetBcryptCost(4); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// state выбирает из базы всё, что повторная загрузка фикстур не должна менять
+type state struct {
+	Users   []models.User
+	Servers []models.Server
+	IPs     []models.IP
+}
+
+func snapshot(t *testing.T, db *gorm.DB) state {
+	t.Helper()
+	var s state
+	if err := db.Order("id").Find(&s.Users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Unscoped().Order("id").Find(&s.Servers).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Order("id").Find(&s.IPs).Error; err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLoadTwice(t *testing.T) {
+	db := newTestDB(t)
+	if err := fixtures.LoadFile(db, "example.yaml"); err != nil {
+		t.Fatalf("first load: %v", err)
+	}
+	first := snapshot(t, db)
+	if len(first.Users) == 0 || len(first.Servers) == 0 || len(first.IPs) == 0 {
+		t.Fatalf("example.yaml loaded %d users, %d servers and %d IPs", len(first.Users), len(first.Servers), len(first.IPs))
+	}
+
+	// Повторная загрузка не пересоздаёт записи: те же идентификаторы, хеши паролей и IP-адреса
+	if err := fixtures.LoadFile(db, "example.yaml"); err != nil {
+		t.Fatalf("second load: %v", err)
+	}
+	second := snapshot(t, db)
+	if !reflect.DeepEqual(first.IPs, second.IPs) {
+		t.Errorf("IPs changed on reload:\n%+v\n%+v", first.IPs, second.IPs)
+	}
+	if len(first.Users) != len(second.Users) || len(first.Servers) != len(second.Servers) {
+		t.Fatalf("reload changed the number of users or servers: %d/%d, %d/%d", len(first.Users), len(second.Users), len(first.Servers), len(second.Servers))
+	}
+	for i := range first.Users {
+		if first.Users[i].ID != second.Users[i].ID || first.Users[i].Password != second.Users[i].Password {
+			t.Errorf("user %s changed on reload", first.Users[i].Username)
+		}
+	}
+	for i := range first.Servers {
+		if first.Servers[i].ID != second.Servers[i].ID || first.Servers[i].ServerNumber != second.Servers[i].ServerNumber {
+			t.Errorf("server %d changed on reload", first.Servers[i].ServerNumber)
+		}
+	}
+}
+
+func TestLoadKeepsIPIDs(t *testing.T) {
+	db := newTestDB(t)
+	doc := &fixtures.Document{
+		Users: []models.UserSpec{{Username: "test", Password: "secret"}},
+		Servers: []models.ServerSpec{{ServerNumber: 321, Username: "test", Product: "AX41", DC: "FSN1-DC14",
+			IPs: []models.IPSpec{{IP: "203.0.113.1", Mask: "32"}, {IP: "203.0.113.2", Mask: "32"}}}},
+	}
+	if err := fixtures.Load(db, doc); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ids := func() map[string]int {
+		t.Helper()
+		result := map[string]int{}
+		for _, ip := range snapshot(t, db).IPs {
+			result[ip.IPAddress] = ip.ID
+		}
+		return result
+	}
+	before := ids()
+
+	// Адрес убран, адрес добавлен: оставшийся сохраняет свой идентификатор, новый получает следующий
+	doc.Servers[0].IPs = []models.IPSpec{{IP: "203.0.113.3", Mask: "32"}, {IP: "203.0.113.2", Mask: "29"}}
+	if err := fixtures.Load(db, doc); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	after := ids()
+	if len(after) != 2 || after["203.0.113.2"] != before["203.0.113.2"] || after["203.0.113.3"] == 0 ||
+		after["203.0.113.3"] == before["203.0.113.1"] || after["203.0.113.3"] == before["203.0.113.2"] {
+		t.Errorf("IP ids %v after reload, before %v", after, before)
+	}
+
+	// Явный идентификатор в фикстурах важнее сохранённого
+	doc.Servers[0].IPs = []models.IPSpec{{ID: 100, IP: "203.0.113.2", Mask: "29"}}
+	if err := fixtures.Load(db, doc); err != nil {
+		t.Fatalf("reload with an explicit id: %v", err)
+	}
+	if got := ids(); !reflect.DeepEqual(got, map[string]int{"203.0.113.2": 100}) {
+		t.Errorf("IP ids = %v, want 203.0.113.2 with id 100", got)
+	}
+}
+
+func TestExportLoadsBack(t *testing.T) {
+	db := newTestDB(t)
+	if err := fixtures.LoadFile(db, "example.yaml"); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	exported, err := fixtures.Export(db)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	copied := newTestDB(t)
+	if err := fixtures.Load(copied, exported); err != nil {
+		t.Fatalf("Load exported: %v", err)
+	}
+	again, err := fixtures.Export(copied)
+	if err != nil {
+		t.Fatalf("Export copy: %v", err)
+	}
+	if !reflect.DeepEqual(exported, again) {
+		t.Errorf("export of the copy differs:\n%+v\n%+v", exported, again)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuthCache возвращает размер кэша проверенных паролей и число попаданий
+func GetAuthCache(cache *middlewares.CredentialCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cache == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": cache.Stats()})
+	}
+}
+
+// ClearAuthCache очищает кэш: следующий запрос каждого пользователя снова проверяется bcrypt
+func ClearAuthCache(cache *middlewares.CredentialCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cache != nil {
+			cache.Clear()
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
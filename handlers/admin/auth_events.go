@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListAuthEvents возвращает журнал аудита аутентификации с фильтрами username, source_ip, result, reason, since, until и limit
+func ListAuthEvents(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := models.AuthEventFilter{
+			Username: c.Query("username"),
+			SourceIP: c.Query("source_ip"),
+			Result:   c.Query("result"),
+			Reason:   c.Query("reason"),
+		}
+
+		var err error
+		if value := c.Query("since"); value != "" {
+			if filter.Since, err = time.Parse(time.RFC3339, value); err != nil {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid since, expected RFC3339")
+				return
+			}
+		}
+		if value := c.Query("until"); value != "" {
+			if filter.Until, err = time.Parse(time.RFC3339, value); err != nil {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid until, expected RFC3339")
+				return
+			}
+		}
+		if value := c.Query("limit"); value != "" {
+			if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid limit")
+				return
+			}
+		}
+
+		events, err := models.FindAuthEvents(db, filter)
+		if err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve auth events")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"count":  len(events),
+			"events": events,
+		})
+	}
+}
+
+// ClearAuthEvents очищает журнал аудита аутентификации
+func ClearAuthEvents(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := db.Where("1 = 1").Delete(&models.AuthEvent{}).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to clear auth events")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
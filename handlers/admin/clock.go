@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"hetzner-api-emulator/clock"
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clockResponse состояние виртуальных часов
+func clockResponse(clk *clock.Virtual) gin.H {
+	return gin.H{
+		"clock": gin.H{
+			"now":    clk.Now().UTC().Format(time.RFC3339),
+			"frozen": clk.Frozen(),
+		},
+	}
+}
+
+// GetClock возвращает текущее виртуальное время
+func GetClock(clk *clock.Virtual) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, clockResponse(clk))
+	}
+}
+
+// FreezeClock останавливает часы
+func FreezeClock(clk *clock.Virtual) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clk.Freeze()
+		c.JSON(http.StatusOK, clockResponse(clk))
+	}
+}
+
+// UnfreezeClock снова запускает часы
+func UnfreezeClock(clk *clock.Virtual) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clk.Unfreeze()
+		c.JSON(http.StatusOK, clockResponse(clk))
+	}
+}
+
+// SetClock переставляет часы на время из параметра time (RFC3339 или yyyy-MM-dd)
+func SetClock(clk *clock.Virtual) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.PostForm("time")
+		if value == "" {
+			value = c.Query("time")
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid time, expected RFC3339 or yyyy-MM-dd")
+			return
+		}
+
+		clk.Set(t)
+		c.JSON(http.StatusOK, clockResponse(clk))
+	}
+}
+
+// AdvanceClock переводит часы вперёд на duration (например, 36h) и/или days
+func AdvanceClock(clk *clock.Virtual) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var total time.Duration
+
+		if value := c.DefaultPostForm("duration", c.Query("duration")); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid duration, expected a positive Go duration such as 36h")
+				return
+			}
+			total += d
+		}
+		if value := c.DefaultPostForm("days", c.Query("days")); value != "" {
+			days, err := strconv.Atoi(value)
+			if err != nil || days < 0 {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid days, expected a positive integer")
+				return
+			}
+			total += time.Duration(days) * 24 * time.Hour
+		}
+		if total == 0 {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "duration or days is required")
+			return
+		}
+
+		clk.Advance(total)
+		c.JSON(http.StatusOK, clockResponse(clk))
+	}
+}
+
+// ResetClock возвращает часы к реальному времени
+func ResetClock(clk *clock.Virtual) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clk.Reset()
+		c.JSON(http.StatusOK, clockResponse(clk))
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFaults возвращает текущие правила внедрения сбоев со счётчиками срабатываний
+func GetFaults(faults *middlewares.FaultInjector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"faults": faults.Rules()})
+	}
+}
+
+// ReplaceFaults заменяет все правила массивом из тела запроса
+func ReplaceFaults(faults *middlewares.FaultInjector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rules []middlewares.FaultRule
+		if err := c.ShouldBindJSON(&rules); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body, expected an array of rules")
+			return
+		}
+		if err := faults.SetRules(rules); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"faults": faults.Rules()})
+	}
+}
+
+// AddFault добавляет одно правило
+func AddFault(faults *middlewares.FaultInjector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rule middlewares.FaultRule
+		if err := c.ShouldBindJSON(&rule); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+		if err := faults.AddRule(rule); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"faults": faults.Rules()})
+	}
+}
+
+// ClearFaults удаляет все правила
+func ClearFaults(faults *middlewares.FaultInjector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		faults.Clear()
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListIPs возвращает все IP-адреса, при необходимости только одного сервера (?server_number=)
+func ListIPs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var servers []models.Server
+		query := db.Preload("IPs").Order("server_number")
+		if serverNumber := c.Query("server_number"); serverNumber != "" {
+			query = query.Where("server_number = ?", serverNumber)
+		}
+		if err := query.Find(&servers).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve IPs")
+			return
+		}
+
+		response := []models.IPSpec{}
+		for _, server := range servers {
+			response = append(response, models.NewServerSpec(server).IPs...)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// CreateIP добавляет IP-адрес к серверу
+func CreateIP(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var spec models.IPSpec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+		if spec.ServerNumber == 0 || spec.IP == "" || spec.Mask == "" {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "server_number, ip and mask are required")
+			return
+		}
+
+		var server models.Server
+		if err := db.Where("server_number = ?", spec.ServerNumber).First(&server).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				middlewares.RespondWithError(c, http.StatusNotFound, "SERVER_NOT_FOUND", "Server with number "+strconv.Itoa(spec.ServerNumber)+" not found")
+				return
+			}
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		ip := models.IP{ID: spec.ID, ServerID: server.ID, IPAddress: spec.IP, Mask: spec.Mask}
+		if err := db.Create(&ip).Error; err != nil {
+			if models.IsDuplicateKey(db, err) {
+				middlewares.RespondWithError(c, http.StatusConflict, "CONFLICT", "IP with this id already exists")
+				return
+			}
+			log.Printf("Error creating IP %s: %v", ip.IPAddress, err)
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to create IP")
+			return
+		}
+
+		c.JSON(http.StatusCreated, models.IPSpec{ID: ip.ID, ServerNumber: server.ServerNumber, IP: ip.IPAddress, Mask: ip.Mask})
+	}
+}
+
+// UpdateIP меняет адрес или маску
+func UpdateIP(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip, ok := loadIP(c, db)
+		if !ok {
+			return
+		}
+
+		var spec models.IPSpec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+		if spec.IP != "" {
+			ip.IPAddress = spec.IP
+		}
+		if spec.Mask != "" {
+			ip.Mask = spec.Mask
+		}
+		if err := db.Save(ip).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update IP")
+			return
+		}
+
+		var server models.Server
+		if err := db.Unscoped().Select("server_number").First(&server, ip.ServerID).Error; err != nil {
+			log.Printf("Error loading server of IP %d: %v", ip.ID, err)
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+		c.JSON(http.StatusOK, models.IPSpec{ID: ip.ID, ServerNumber: server.ServerNumber, IP: ip.IPAddress, Mask: ip.Mask})
+	}
+}
+
+// DeleteIP удаляет IP-адрес
+func DeleteIP(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip, ok := loadIP(c, db)
+		if !ok {
+			return
+		}
+		if err := db.Delete(ip).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to delete IP")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// loadIP загружает IP-адрес по параметру :id
+func loadIP(c *gin.Context, db *gorm.DB) (*models.IP, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid IP id format")
+		return nil, false
+	}
+
+	var ip models.IP
+	if err := db.First(&ip, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middlewares.RespondWithError(c, http.StatusNotFound, "IP_NOT_FOUND", "IP with id "+c.Param("id")+" not found")
+			return nil, false
+		}
+		middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return nil, false
+	}
+	return &ip, true
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lockoutResponse настройки блокировки и действующие блокировки
+func lockoutResponse(lockout *middlewares.LoginLockout) gin.H {
+	return gin.H{
+		"settings": lockout.Settings(),
+		"blocked":  lockout.Blocks(),
+	}
+}
+
+// GetLockout возвращает настройки блокировки после неудачных входов и заблокированные адреса
+func GetLockout(lockout *middlewares.LoginLockout) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, lockoutResponse(lockout))
+	}
+}
+
+// ReplaceLockout заменяет настройки блокировки ({"max_failures": 3, "window": 600, "block_for": 600}; max_failures 0 отключает)
+func ReplaceLockout(lockout *middlewares.LoginLockout) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var settings middlewares.LockoutSettings
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+		if err := settings.Validate(); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+			return
+		}
+		lockout.SetSettings(settings)
+		c.JSON(http.StatusOK, lockoutResponse(lockout))
+	}
+}
+
+// ClearLockoutBlocks снимает блокировку адреса из ?ip= или, без параметра, все блокировки
+func ClearLockoutBlocks(lockout *middlewares.LoginLockout) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ip := c.Query("ip"); ip != "" {
+			lockout.Unblock(ip)
+		} else {
+			lockout.Reset()
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hetzner-api-emulator/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOpenAPI отдаёт описание OpenAPI маршрутов Robot, которые реализует эмулятор
+func GetOpenAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", openapi.Spec())
+	}
+}
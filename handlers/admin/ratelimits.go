@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitsResponse квоты и их текущее использование
+func rateLimitsResponse(limiter *middlewares.RateLimiter) gin.H {
+	return gin.H{
+		"rate_limits": limiter.Limits(),
+		"usage":       limiter.Usage(),
+	}
+}
+
+// GetRateLimits возвращает квоты и использование
+func GetRateLimits(limiter *middlewares.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, rateLimitsResponse(limiter))
+	}
+}
+
+// ReplaceRateLimits заменяет квоты массивом из тела запроса (пустой массив отключает ограничения)
+func ReplaceRateLimits(limiter *middlewares.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var limits []middlewares.RateLimit
+		if err := c.ShouldBindJSON(&limits); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body, expected an array of rate limits")
+			return
+		}
+		for _, limit := range limits {
+			if limit.Group == "" || limit.MaxRequest <= 0 || limit.Interval <= 0 {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "group, max_request and interval are required")
+				return
+			}
+		}
+		limiter.SetLimits(limits)
+		c.JSON(http.StatusOK, rateLimitsResponse(limiter))
+	}
+}
+
+// ResetRateLimitUsage обнуляет счётчики запросов
+func ResetRateLimitUsage(limiter *middlewares.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter.Reset()
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"hetzner-api-emulator/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRequests возвращает журнал запросов с фильтрами method, path, user_id, status, fault, since и until
+func ListRequests(journal *middlewares.Journal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := middlewares.JournalFilter{
+			Method: c.Query("method"),
+			Path:   c.Query("path"),
+			Fault:  c.Query("fault"),
+		}
+
+		var err error
+		if value := c.Query("user_id"); value != "" {
+			if filter.UserID, err = strconv.Atoi(value); err != nil {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid user_id")
+				return
+			}
+		}
+		if value := c.Query("status"); value != "" {
+			if filter.Status, err = strconv.Atoi(value); err != nil {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid status")
+				return
+			}
+		}
+		if value := c.Query("since"); value != "" {
+			if filter.Since, err = time.Parse(time.RFC3339, value); err != nil {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid since, expected RFC3339")
+				return
+			}
+		}
+		if value := c.Query("until"); value != "" {
+			if filter.Until, err = time.Parse(time.RFC3339, value); err != nil {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid until, expected RFC3339")
+				return
+			}
+		}
+
+		entries := journal.Entries(filter)
+		c.JSON(http.StatusOK, gin.H{
+			"count":    len(entries),
+			"requests": entries,
+		})
+	}
+}
+
+// ClearRequests очищает журнал запросов
+func ClearRequests(journal *middlewares.Journal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		journal.Clear()
+		c.Status(http.StatusNoContent)
+	}
+}
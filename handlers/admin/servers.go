@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListServers возвращает все серверы, при необходимости только одного пользователя (?user_id=).
+// Снятые после даты отмены серверы отдаются с ?include_gone=true
+func ListServers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := db.Preload("IPs").Order("server_number")
+		if c.Query("include_gone") == "true" {
+			query = query.Unscoped()
+		}
+		if userID := c.Query("user_id"); userID != "" {
+			query = query.Where("user_id = ?", userID)
+		}
+
+		var servers []models.Server
+		if err := query.Find(&servers).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve servers")
+			return
+		}
+
+		response := []models.ServerSpec{}
+		for _, server := range servers {
+			response = append(response, models.NewServerSpec(server))
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetServer возвращает сервер по номеру вне зависимости от владельца
+func GetServer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		server, ok := loadServer(c, db)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, models.NewServerSpec(*server))
+	}
+}
+
+// CreateServer создаёт сервер с IP-адресами
+func CreateServer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var spec models.ServerSpec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+		if spec.ServerNumber <= 0 {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "server_number is required")
+			return
+		}
+
+		var count int64
+		if err := db.Unscoped().Model(&models.Server{}).Where("server_number = ?", spec.ServerNumber).Count(&count).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+		if count > 0 {
+			middlewares.RespondWithError(c, http.StatusConflict, "SERVER_ALREADY_EXISTS", "Server with number "+strconv.Itoa(spec.ServerNumber)+" already exists")
+			return
+		}
+
+		server, err := models.UpsertServer(db, spec)
+		if err != nil {
+			respondUpsertServerError(c, db, spec, err)
+			return
+		}
+		c.JSON(http.StatusCreated, models.NewServerSpec(*server))
+	}
+}
+
+// UpdateServer полностью заменяет описание сервера и его IP-адреса
+func UpdateServer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		existing, ok := loadServer(c, db)
+		if !ok {
+			return
+		}
+
+		var spec models.ServerSpec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+		spec.ServerNumber = existing.ServerNumber
+		if spec.UserID == 0 && spec.Username == "" {
+			spec.UserID = existing.UserID
+		}
+
+		server, err := models.UpsertServer(db, spec)
+		if err != nil {
+			respondUpsertServerError(c, db, spec, err)
+			return
+		}
+		c.JSON(http.StatusOK, models.NewServerSpec(*server))
+	}
+}
+
+// DeleteServer удаляет сервер и его IP-адреса
+func DeleteServer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		server, ok := loadServer(c, db)
+		if !ok {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("server_id = ?", server.ID).Delete(&models.IP{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(server).Error
+		})
+		if err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to delete server")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// respondUpsertServerError отвечает на ошибку UpsertServer: текст ошибки описания возвращается клиенту,
+// конфликт идентификаторов — 409, ошибка базы только пишется в журнал
+func respondUpsertServerError(c *gin.Context, db *gorm.DB, spec models.ServerSpec, err error) {
+	switch {
+	case models.IsSpecError(err):
+		middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+	case models.IsDuplicateKey(db, err):
+		middlewares.RespondWithError(c, http.StatusConflict, "CONFLICT", "Server "+strconv.Itoa(spec.ServerNumber)+" or one of its IP ids already exists")
+	default:
+		log.Printf("Error saving server %d: %v", spec.ServerNumber, err)
+		middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to save server")
+	}
+}
+
+// loadServer загружает сервер по параметру :server-number вместе с IP-адресами, включая снятые серверы
+func loadServer(c *gin.Context, db *gorm.DB) (*models.Server, bool) {
+	serverNumber, err := strconv.Atoi(c.Param("server-number"))
+	if err != nil {
+		middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_SERVER_NUMBER", "Invalid server number format")
+		return nil, false
+	}
+
+	var server models.Server
+	if err := db.Unscoped().Preload("IPs").Where("server_number = ?", serverNumber).First(&server).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middlewares.RespondWithError(c, http.StatusNotFound, "SERVER_NOT_FOUND", "Server with number "+strconv.Itoa(serverNumber)+" not found")
+			return nil, false
+		}
+		middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return nil, false
+	}
+	return &server, true
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// State полное состояние эмулятора по всем пользователям
+type State struct {
+	Users   []models.UserSpec   `json:"users"`
+	Servers []models.ServerSpec `json:"servers"`
+}
+
+// GetState возвращает всех пользователей и все серверы с IP-адресами, включая снятые после даты отмены
+func GetState(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var users []models.User
+		if err := db.Order("id").Find(&users).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve users")
+			return
+		}
+
+		var servers []models.Server
+		if err := db.Unscoped().Preload("IPs").Order("server_number").Find(&servers).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve servers")
+			return
+		}
+
+		state := State{Users: []models.UserSpec{}, Servers: []models.ServerSpec{}}
+		for _, user := range users {
+			state.Users = append(state.Users, models.NewUserSpec(user))
+		}
+		for _, server := range servers {
+			state.Servers = append(state.Servers, models.NewServerSpec(server))
+		}
+		c.JSON(http.StatusOK, state)
+	}
+}
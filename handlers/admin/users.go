@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListUsers возвращает всех пользователей эмулятора
+func ListUsers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var users []models.User
+		if err := db.Order("id").Find(&users).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve users")
+			return
+		}
+
+		response := []models.UserSpec{}
+		for _, user := range users {
+			response = append(response, models.NewUserSpec(user))
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetUser возвращает пользователя по идентификатору
+func GetUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadUser(c, db)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, models.NewUserSpec(*user))
+	}
+}
+
+// createUserRequest тело POST /__admin/users
+type createUserRequest struct {
+	models.UserSpec
+	GenerateUsername bool `json:"generate_username"` // Сгенерировать имя вида #ws+XXXXXXXX
+}
+
+// CreateUser создаёт пользователя, пароль передаётся в открытом виде и хешируется bcrypt.
+// Без пароля он генерируется и возвращается в ответе один раз
+func CreateUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request createUserRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+		spec := request.UserSpec
+
+		if request.GenerateUsername {
+			if spec.Username != "" {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "username and generate_username are mutually exclusive")
+				return
+			}
+			username, err := models.GenerateUsername(db)
+			if err != nil {
+				middlewares.RespondWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to generate username")
+				return
+			}
+			spec.Username = username
+		}
+		if spec.Username == "" {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "username or generate_username is required")
+			return
+		}
+
+		generated := spec.Password == ""
+		if generated {
+			password, err := models.GeneratePassword()
+			if err != nil {
+				middlewares.RespondWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to generate password")
+				return
+			}
+			spec.Password = password
+		}
+
+		hashedPassword, err := models.HashPassword(spec.Password)
+		if err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Error hashing password")
+			return
+		}
+
+		user := models.User{ID: spec.ID, Username: spec.Username, Password: hashedPassword, Disabled: spec.Disabled}
+		if err := user.SetScopes(spec.Scopes); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+			return
+		}
+		if err := user.SetAllowedIPs(spec.AllowedIPs); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+			return
+		}
+		if err := db.Create(&user).Error; err != nil {
+			// Клиенту только общий код: текст ошибки драйвера раскрывает схему базы
+			if models.IsDuplicateKey(db, err) {
+				middlewares.RespondWithError(c, http.StatusConflict, "USER_ALREADY_EXISTS", "User with this username or id already exists")
+				return
+			}
+			log.Printf("Error creating user %s: %v", user.Username, err)
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to create user")
+			return
+		}
+
+		response := models.NewUserSpec(user)
+		if generated {
+			response.Password = spec.Password
+		}
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+// UpdateUser меняет имя и/или пароль пользователя
+func UpdateUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadUser(c, db)
+		if !ok {
+			return
+		}
+
+		var spec models.UserSpec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+			return
+		}
+
+		if spec.Username != "" {
+			user.Username = spec.Username
+		}
+		if spec.Password != "" {
+			hashedPassword, err := models.HashPassword(spec.Password)
+			if err != nil {
+				middlewares.RespondWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Error hashing password")
+				return
+			}
+			user.Password = hashedPassword
+		}
+
+		if err := db.Save(user).Error; err != nil {
+			if models.IsDuplicateKey(db, err) {
+				middlewares.RespondWithError(c, http.StatusConflict, "USER_ALREADY_EXISTS", "User with this username already exists")
+				return
+			}
+			log.Printf("Error updating user %d: %v", user.ID, err)
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update user")
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewUserSpec(*user))
+	}
+}
+
+// DeleteUser удаляет пользователя вместе с его серверами и IP-адресами
+func DeleteUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadUser(c, db)
+		if !ok {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var serverIDs []int
+			if err := tx.Unscoped().Model(&models.Server{}).Where("user_id = ?", user.ID).Pluck("id", &serverIDs).Error; err != nil {
+				return err
+			}
+			if len(serverIDs) > 0 {
+				if err := tx.Where("server_id IN ?", serverIDs).Delete(&models.IP{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Where("id IN ?", serverIDs).Delete(&models.Server{}).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Delete(user).Error
+		})
+		if err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to delete user")
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// SetUserDisabled отключает или включает учётную запись. Данные пользователя сохраняются
+func SetUserDisabled(db *gorm.DB, disabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadUser(c, db)
+		if !ok {
+			return
+		}
+
+		user.Disabled = disabled
+		if err := db.Model(user).UpdateColumn("disabled", disabled).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update user")
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewUserSpec(*user))
+	}
+}
+
+// RotateUserPassword меняет пароль на переданный в теле ({"password": "..."}) или на сгенерированный.
+// Сгенерированный пароль возвращается в ответе один раз
+func RotateUserPassword(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadUser(c, db)
+		if !ok {
+			return
+		}
+
+		var request struct {
+			Password string `json:"password"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&request); err != nil {
+				middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body")
+				return
+			}
+		}
+
+		generated := request.Password == ""
+		if generated {
+			password, err := models.GeneratePassword()
+			if err != nil {
+				middlewares.RespondWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to generate password")
+				return
+			}
+			request.Password = password
+		}
+
+		hashedPassword, err := models.HashPassword(request.Password)
+		if err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Error hashing password")
+			return
+		}
+		user.Password = hashedPassword
+		if err := db.Model(user).UpdateColumn("password", hashedPassword).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update password")
+			return
+		}
+
+		response := models.NewUserSpec(*user)
+		if generated {
+			response.Password = request.Password
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// SetUserScopes заменяет области доступа пользователя ({"scopes": ["server:read"]}); пустой список снимает ограничения
+func SetUserScopes(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadUser(c, db)
+		if !ok {
+			return
+		}
+
+		var request struct {
+			Scopes []string `json:"scopes"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body, expected {\"scopes\": [...]}")
+			return
+		}
+		if err := user.SetScopes(request.Scopes); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+			return
+		}
+		if err := db.Model(user).UpdateColumn("scopes", user.Scopes).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update scopes")
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewUserSpec(*user))
+	}
+}
+
+// SetUserAllowedIPs заменяет список адресов и подсетей, с которых пользователю разрешён доступ
+// ({"allowed_ips": ["203.0.113.10", "198.51.100.0/24"]}); пустой список снимает ограничение
+func SetUserAllowedIPs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := loadUser(c, db)
+		if !ok {
+			return
+		}
+
+		var request struct {
+			AllowedIPs []string `json:"allowed_ips"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid request body, expected {\"allowed_ips\": [...]}")
+			return
+		}
+		if err := user.SetAllowedIPs(request.AllowedIPs); err != nil {
+			middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", err.Error())
+			return
+		}
+		if err := db.Model(user).UpdateColumn("allowed_ips", user.AllowedIPs).Error; err != nil {
+			middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update allowed IPs")
+			return
+		}
+
+		c.JSON(http.StatusOK, models.NewUserSpec(*user))
+	}
+}
+
+// loadUser загружает пользователя по параметру :id и сам отвечает ошибкой, если это не удалось
+func loadUser(c *gin.Context, db *gorm.DB) (*models.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Invalid user id format")
+		return nil, false
+	}
+
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middlewares.RespondWithError(c, http.StatusNotFound, "USER_NOT_FOUND", "User with id "+c.Param("id")+" not found")
+			return nil, false
+		}
+		middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return nil, false
+	}
+	return &user, true
+}
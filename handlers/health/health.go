@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"hetzner-api-emulator/migrations"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pingTimeout сколько ждать ответа базы в проверках
+const pingTimeout = 2 * time.Second
+
+// pingDatabase проверяет, что соединение с базой живо
+func pingDatabase(c *gin.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), pingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// Healthz проверка живости: процесс отвечает и база доступна. Авторизация не нужна, поэтому
+// ошибка базы пишется только в журнал: её текст может содержать адрес и пользователя базы
+func Healthz(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := pingDatabase(c, db); err != nil {
+			log.Printf("Health check: database unreachable: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "database": "unreachable"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// Readyz проверка готовности: база доступна и все миграции схемы применены, эмулятор может обслуживать Robot API
+func Readyz(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := pingDatabase(c, db); err != nil {
+			log.Printf("Readiness check: database unreachable: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "database": "unreachable"})
+			return
+		}
+		pending, err := migrations.Pending(db.WithContext(c.Request.Context()))
+		if err != nil {
+			log.Printf("Readiness check: failed to read schema version: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "database": "schema version unknown"})
+			return
+		}
+		if pending > 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "database": "schema not migrated", "pending_migrations": pending})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "schema_version": migrations.Latest()})
+	}
+}
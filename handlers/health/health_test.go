@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/migrations"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T, migrate bool) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	if migrate {
+		if _, err := migrations.Up(db, 0); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+	}
+	return db
+}
+
+// check выполняет GET path и возвращает статус и тело ответа
+func check(t *testing.T, db *gorm.DB, path string) (int, map[string]any) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", Healthz(db))
+	router.GET("/readyz", Readyz(db))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", recorder.Body, err)
+	}
+	return recorder.Code, body
+}
+
+func TestHealthChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		migrate bool
+		closed  bool
+		path    string
+		want    int
+		reason  string
+	}{
+		{"alive", true, false, "/healthz", http.StatusOK, ""},
+		{"ready", true, false, "/readyz", http.StatusOK, ""},
+		{"alive without schema", false, false, "/healthz", http.StatusOK, ""},
+		{"not ready without schema", false, false, "/readyz", http.StatusServiceUnavailable, "schema not migrated"},
+		{"database closed", true, true, "/healthz", http.StatusServiceUnavailable, "unreachable"},
+		{"not ready with database closed", true, true, "/readyz", http.StatusServiceUnavailable, "unreachable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, tt.migrate)
+			if tt.closed {
+				sqlDB, err := db.DB()
+				if err != nil {
+					t.Fatal(err)
+				}
+				sqlDB.Close()
+			}
+
+			status, body := check(t, db, tt.path)
+			if status != tt.want {
+				t.Errorf("status = %d, want %d, body %v", status, tt.want, body)
+			}
+			// Наружу отдаётся только причина, без текста ошибки драйвера
+			if reason, _ := body["database"].(string); reason != tt.reason {
+				t.Errorf("database = %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestReadyzReportsSchemaVersion(t *testing.T) {
+	_, body := check(t, newTestDB(t, true), "/readyz")
+	if body["status"] != "ready" || body["schema_version"] != float64(migrations.Latest()) {
+		t.Errorf("body = %v, want ready at version %d", body, migrations.Latest())
+	}
+
+	_, body = check(t, newTestDB(t, false), "/readyz")
+	if body["pending_migrations"] != float64(migrations.Latest()) {
+		t.Errorf("body = %v, want %d pending migrations", body, migrations.Latest())
+	}
+}
@@ -8,25 +8,6 @@ import (
 	"gorm.io/gorm"
 )
 
-
-// ServerResponse структура для отдачи данных в нужном формате
-type ServerResponseShort struct {
-	Server struct {
-		ServerIP      string   `json:"server_ip"`
-		ServerIPv6Net string   `json:"server_ipv6_net"`
-		ServerNumber  int      `json:"server_number"`
-		ServerName    string   `json:"server_name"`
-		Product       string   `json:"product"`
-		DC            string   `json:"dc"`
-		Traffic       string   `json:"traffic"`
-		Status        string   `json:"status"`
-		Cancelled     bool     `json:"cancelled"`
-		PaidUntil     string   `json:"paid_until"`
-		IP            []string `json:"ip"`
-		Subnet        []models.Subnet `json:"subnet"`
-	} `json:"server"`
-}
-
 func GetServers(db *gorm.DB) gin.HandlerFunc {
     return func(c *gin.Context) {
         userId, err := middlewares.GetUserIDFromContext(c)
@@ -49,9 +30,9 @@ func GetServers(db *gorm.DB) gin.HandlerFunc {
             return
         }
 
-        var serverResponses []ServerResponseShort
+        var serverResponses []models.ServerResponseShort
         for _, server := range servers {
-            var serverResponse ServerResponseShort
+            var serverResponse models.ServerResponseShort
             // Заполнение информации о сервере
             serverResponse.Server.ServerNumber = server.ServerNumber
             serverResponse.Server.ServerName = server.ServerName
@@ -67,7 +48,7 @@ func GetServers(db *gorm.DB) gin.HandlerFunc {
             serverResponse.Server.ServerIPv6Net = server.ServerIPv6Net
         
             // Получаем IP-адреса для сервера из связанной таблицы IPs
-            var ipAddresses []string
+            ipAddresses := []string{} // Robot всегда отдаёт массив, даже пустой
             for _, ip := range server.IPs {
                 ipAddresses = append(ipAddresses, ip.IPAddress)
             }
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"hetzner-api-emulator/middlewares"
 	"hetzner-api-emulator/models"
 	"net/http"
@@ -12,21 +11,8 @@ import (
 
 func GetServerByNumber(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId, err := middlewares.GetUserIDFromContext(c)
-		if err != nil || userId == 0 {
-			middlewares.RespondWithError(c, http.StatusBadRequest, "USER_ID_MISSING", "User ID is missing")
-			return
-		}
-
-		serverNumber := c.Param("server-number")
-
-		var server models.Server
-		if err := db.Where("server_number = ?", serverNumber).First(&server).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				middlewares.RespondWithError(c, http.StatusNotFound, "SERVER_NOT_FOUND", fmt.Sprintf("Server with id %s not found", serverNumber))
-			} else {
-				middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
-			}
+		server, ok := loadOwnedServer(c, db)
+		if !ok {
 			return
 		}
 
@@ -42,7 +28,9 @@ func GetServerByNumber(db *gorm.DB) gin.HandlerFunc {
 		response.Server.Traffic = server.Traffic
 		response.Server.Status = server.Status
 		response.Server.Cancelled = server.Cancelled
-		response.Server.PaidUntil = server.PaidUntil.Format("2006-01-02")
+		if server.PaidUntil != nil {
+			response.Server.PaidUntil = server.PaidUntil.Format("2006-01-02")
+		}
 		response.Server.ServerIPv6Net = server.ServerIPv6Net
 
 		// Прямо добавляем экстра параметры
@@ -63,6 +51,7 @@ func GetServerByNumber(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		response.Server.IP = []string{} // Robot всегда отдаёт массив, даже пустой
 		for _, ip := range ips {
 			response.Server.IP = append(response.Server.IP, ip.IPAddress)
 			response.Server.Subnet = append(response.Server.Subnet, models.Subnet{
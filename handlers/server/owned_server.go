@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loadOwnedServer загружает сервер из параметра server-number, принадлежащий текущему пользователю.
+// Чужой сервер неотличим от несуществующего: в обоих случаях SERVER_NOT_FOUND.
+// При ошибке ответ уже отправлен и возвращается false
+func loadOwnedServer(c *gin.Context, db *gorm.DB) (*models.Server, bool) {
+	userId, err := middlewares.GetUserIDFromContext(c)
+	if err != nil || userId == 0 {
+		middlewares.RespondWithError(c, http.StatusBadRequest, "USER_ID_MISSING", "User ID is missing")
+		return nil, false
+	}
+
+	serverNumber, err := strconv.Atoi(c.Param("server-number"))
+	if err != nil {
+		middlewares.RespondWithRobotError(c, middlewares.NewInvalidInput(nil, []string{"server-number"}))
+		return nil, false
+	}
+
+	var server models.Server
+	if err := db.Where("user_id = ? AND server_number = ?", userId, serverNumber).Limit(1).Find(&server).Error; err != nil {
+		log.Printf("Error querying database: %v", err)
+		middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return nil, false
+	}
+	if server.ID == 0 {
+		middlewares.RespondWithError(c, http.StatusNotFound, "SERVER_NOT_FOUND", fmt.Sprintf("Server with id %d not found", serverNumber))
+		return nil, false
+	}
+	return &server, true
+}
@@ -1,11 +1,9 @@
 package handlers
 
 import (
-	"errors"
 	"hetzner-api-emulator/middlewares"
 	"hetzner-api-emulator/models"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,41 +11,21 @@ import (
 
 func UpdateServerName(db *gorm.DB) gin.HandlerFunc {
     return func(c *gin.Context) {
-        userId, err := middlewares.GetUserIDFromContext(c)
-        if err != nil || userId == 0 {
-            middlewares.RespondWithError(c, http.StatusBadRequest, "USER_ID_MISSING", "User ID is missing")
-            return
-        }
-
-        // Получаем server_number из параметров
-        serverNumberStr := c.Param("server-number")
-        serverNumber, err := strconv.Atoi(serverNumberStr)
-        if err != nil {
-            middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_SERVER_NUMBER", "Invalid server number format")
+        server, ok := loadOwnedServer(c, db)
+        if !ok {
             return
         }
 
         // Получаем новое имя сервера
         serverName := c.DefaultPostForm("server_name", "")
         if serverName == "" {
-            middlewares.RespondWithError(c, http.StatusBadRequest, "INVALID_INPUT", "Server name is required")
-            return
-        }
-
-        // Обновляем имя сервера
-        var server models.Server
-        if err := db.Where("user_id = ? AND server_number = ?", userId, serverNumber).First(&server).Error; err != nil {
-            if errors.Is(err, gorm.ErrRecordNotFound) {
-                middlewares.RespondWithError(c, http.StatusNotFound, "SERVER_NOT_FOUND", "Server not found")
-                return
-            }
-            middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+            middlewares.RespondWithRobotError(c, middlewares.NewInvalidInput([]string{"server_name"}, nil))
             return
         }
 
         // Обновляем имя сервера
         server.ServerName = serverName
-        if err := db.Save(&server).Error; err != nil {
+        if err := db.Save(server).Error; err != nil {
             middlewares.RespondWithError(c, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to update server name")
             return
         }
@@ -74,6 +52,7 @@ func UpdateServerName(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		serverResponse.Server.IP = []string{} // Robot всегда отдаёт массив, даже пустой
 		for _, ip := range ips {
 			serverResponse.Server.IP = append(serverResponse.Server.IP, ip.IPAddress)
 			serverResponse.Server.Subnet = append(serverResponse.Server.Subnet, models.Subnet{
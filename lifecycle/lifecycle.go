@@ -0,0 +1,136 @@
+package lifecycle
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"hetzner-api-emulator/clock"
+	"hetzner-api-emulator/models"
+
+	"gorm.io/gorm"
+)
+
+// Mode определяет, что происходит с сервером после даты отмены
+type Mode string
+
+const (
+	// ModeDelete удаляет сервер из базы
+	ModeDelete Mode = "delete"
+	// ModeMark помечает сервер удалённым (soft delete): Robot API его больше не видит, административный API видит
+	ModeMark Mode = "mark"
+)
+
+// ParseMode разбирает режим из конфигурации
+func ParseMode(value string) (Mode, error) {
+	switch Mode(value) {
+	case "", ModeDelete:
+		return ModeDelete, nil
+	case ModeMark:
+		return ModeMark, nil
+	default:
+		return "", fmt.Errorf("unknown lifecycle mode %q, expected delete or mark", value)
+	}
+}
+
+// ExpireCancelledServers завершает серверы, у которых дата отмены уже прошла (сервер работает до конца дня отмены):
+// освобождает IP-адреса и подсети, отвязывает storage box и удаляет или помечает сервер.
+// Даты отмены — календарные даты в UTC, поэтому и текущий день берётся в UTC, а не в поясе now
+func ExpireCancelledServers(db *gorm.DB, now time.Time, mode Mode) (int, error) {
+	utc := now.UTC()
+	today := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+
+	var servers []models.Server
+	if err := db.Where("cancelled = ? AND cancellation_date IS NOT NULL AND cancellation_date < ?", true, today).Find(&servers).Error; err != nil {
+		return 0, err
+	}
+
+	for _, server := range servers {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// IP-адреса и подсети возвращаются в пул вместе с сервером
+			if err := tx.Where("server_id = ?", server.ID).Delete(&models.IP{}).Error; err != nil {
+				return err
+			}
+
+			// В режиме mark время снятия берётся из виртуальных часов, а не из реального времени
+			if mode == ModeMark {
+				return tx.Model(&models.Server{}).Where("id = ?", server.ID).
+					UpdateColumns(map[string]interface{}{"linked_storagebox": 0, "status": "cancelled", "deleted_at": now}).Error
+			}
+			return tx.Unscoped().Delete(&models.Server{}, server.ID).Error
+		})
+		if err != nil {
+			return 0, err
+		}
+		log.Printf("Server %d removed (%s): cancellation date %s has passed", server.ServerNumber, mode, server.CancellationDate.Format("2006-01-02"))
+	}
+	return len(servers), nil
+}
+
+// Worker периодически применяет переходы по времени
+type Worker struct {
+	DB       *gorm.DB
+	Clock    clock.Clock
+	Mode     Mode
+	Interval time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// NewWorker создаёт фоновый обработчик жизненного цикла серверов
+func NewWorker(db *gorm.DB, clk clock.Clock, mode Mode, interval time.Duration) *Worker {
+	return &Worker{DB: db, Clock: clk, Mode: mode, Interval: interval}
+}
+
+// RunOnce применяет переходы на текущее время часов
+func (w *Worker) RunOnce() (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return ExpireCancelledServers(w.DB, w.Clock.Now(), w.Mode)
+}
+
+// Start запускает фоновый цикл; повторный вызов ничего не делает
+func (w *Worker) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil || w.Interval <= 0 {
+		return
+	}
+	w.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := w.RunOnce(); err != nil {
+					log.Printf("Failed to apply time-based transitions: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}(w.stop)
+}
+
+// Stop останавливает фоновый цикл
+func (w *Worker) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
+
+// Attach дополнительно применяет переходы сразу при каждой перестановке виртуальных часов
+func (w *Worker) Attach(clk *clock.Virtual) {
+	clk.OnChange(func(now time.Time) {
+		if _, err := w.RunOnce(); err != nil {
+			log.Printf("Failed to apply time-based transitions: %v", err)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package lifecycle_test
+
+import (
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/clock"
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/lifecycle"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+
+	"gorm.io/gorm"
+)
+
+// newTestDB создаёт базу с отменённым 2030-01-31 сервером 321 и действующим сервером 421
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := models.SetBcryptCost(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.UpsertUser(db, models.UserSpec{Username: "test", Password: "test"}); err != nil {
+		t.Fatalf("UpsertUser: %v", err)
+	}
+	servers := []models.ServerSpec{
+		{ServerNumber: 321, Username: "test", Product: "DS 3000", DC: "NBG1-DC1", LinkedStoragebox: 12345,
+			Cancelled: true, CancellationDate: "2030-01-31", IPs: []models.IPSpec{{IP: "203.0.113.1", Mask: "32"}, {IP: "203.0.113.2", Mask: "32"}}},
+		{ServerNumber: 421, Username: "test", Product: "DS 3000", DC: "NBG1-DC1", IPs: []models.IPSpec{{IP: "203.0.113.3", Mask: "32"}}},
+	}
+	for _, spec := range servers {
+		if _, err := models.UpsertServer(db, spec); err != nil {
+			t.Fatalf("UpsertServer: %v", err)
+		}
+	}
+	return db
+}
+
+// visible сообщает, видит ли сервер Robot API, то есть без снятых серверов
+func visible(t *testing.T, db *gorm.DB, serverNumber int) bool {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.Server{}).Where("server_number = ?", serverNumber).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func countIPs(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.IP{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func expire(t *testing.T, db *gorm.DB, now time.Time, mode lifecycle.Mode) int {
+	t.Helper()
+	expired, err := lifecycle.ExpireCancelledServers(db, now, mode)
+	if err != nil {
+		t.Fatalf("ExpireCancelledServers(%s): %v", now, err)
+	}
+	return expired
+}
+
+func TestExpireDeletesServerAndReleasesIPs(t *testing.T) {
+	db := newTestDB(t)
+
+	// Сервер работает до конца дня отмены
+	if expired := expire(t, db, time.Date(2030, 1, 31, 23, 59, 0, 0, time.UTC), lifecycle.ModeDelete); expired != 0 || !visible(t, db, 321) {
+		t.Fatalf("on the cancellation date: expired %d, want the server kept", expired)
+	}
+	if expired := expire(t, db, time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC), lifecycle.ModeDelete); expired != 1 {
+		t.Fatalf("after the cancellation date: expired %d, want 1", expired)
+	}
+
+	var count int64
+	if err := db.Unscoped().Model(&models.Server{}).Where("server_number = ?", 321).Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("server rows = %d, %v, want the row deleted", count, err)
+	}
+	if !visible(t, db, 421) || countIPs(t, db) != 1 {
+		t.Errorf("the active server or its IP was touched: visible %v, IPs %d", visible(t, db, 421), countIPs(t, db))
+	}
+	if expired := expire(t, db, time.Date(2030, 2, 2, 0, 0, 0, 0, time.UTC), lifecycle.ModeDelete); expired != 0 {
+		t.Errorf("second run expired %d servers, want 0", expired)
+	}
+}
+
+func TestExpireMarksServer(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Date(2030, 2, 1, 12, 0, 0, 0, time.UTC)
+	if expired := expire(t, db, now, lifecycle.ModeMark); expired != 1 {
+		t.Fatalf("expired %d, want 1", expired)
+	}
+
+	if visible(t, db, 321) {
+		t.Error("a marked server is visible to the Robot API")
+	}
+	var server models.Server
+	if err := db.Unscoped().Where("server_number = ?", 321).First(&server).Error; err != nil {
+		t.Fatalf("marked server row: %v", err)
+	}
+	if server.Status != "cancelled" || server.LinkedStoragebox != 0 || !server.DeletedAt.Time.Equal(now) {
+		t.Errorf("marked server: status %q, storage box %d, deleted at %s", server.Status, server.LinkedStoragebox, server.DeletedAt.Time)
+	}
+	if countIPs(t, db) != 1 {
+		t.Errorf("IPs = %d, want only the active server's IP", countIPs(t, db))
+	}
+	if expired := expire(t, db, now.Add(24*time.Hour), lifecycle.ModeMark); expired != 0 {
+		t.Errorf("a marked server expired again")
+	}
+}
+
+func TestExpireUsesUTCDates(t *testing.T) {
+	// Даты отмены — календарные даты в UTC; часовой пояс процесса и переданного времени не должен их сдвигать
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+
+	for _, zone := range []*time.Location{time.UTC, time.FixedZone("UTC+9", 9*3600), time.FixedZone("UTC-10", -10*3600)} {
+		t.Run(zone.String(), func(t *testing.T) {
+			time.Local = zone
+			db := newTestDB(t)
+
+			lastMinute := time.Date(2030, 1, 31, 23, 59, 0, 0, time.UTC).In(zone)
+			if expired := expire(t, db, lastMinute, lifecycle.ModeDelete); expired != 0 {
+				t.Fatalf("at %s: expired %d, want the server kept until the end of the UTC day", lastMinute, expired)
+			}
+			nextDay := time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC).In(zone)
+			if expired := expire(t, db, nextDay, lifecycle.ModeDelete); expired != 1 {
+				t.Fatalf("at %s: expired %d, want 1", nextDay, expired)
+			}
+		})
+	}
+}
+
+func TestWorkerFollowsClock(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.New()
+	clk.Freeze()
+	clk.Set(time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	worker := lifecycle.NewWorker(db, clk, lifecycle.ModeDelete, 0)
+	worker.Attach(clk)
+
+	clk.Set(time.Date(2030, 1, 31, 12, 0, 0, 0, time.UTC))
+	if !visible(t, db, 321) {
+		t.Fatal("server removed on its cancellation date")
+	}
+	clk.Advance(12 * time.Hour)
+	if visible(t, db, 321) {
+		t.Error("Advance past the cancellation date did not remove the server")
+	}
+}
+
+func TestWorkerSetPastCancellationDate(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.New()
+	lifecycle.NewWorker(db, clk, lifecycle.ModeMark, 0).Attach(clk)
+
+	clk.Set(time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC))
+	if visible(t, db, 321) || !visible(t, db, 421) {
+		t.Errorf("after Set: cancelled server visible %v, active server visible %v", visible(t, db, 321), visible(t, db, 421))
+	}
+}
+
+func TestWorkerStartRunsPeriodically(t *testing.T) {
+	db := newTestDB(t)
+	clk := clock.New()
+	clk.Freeze()
+	// Без уведомления: часы переставляются до подключения обработчика
+	clk.Set(time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	worker := lifecycle.NewWorker(db, clk, lifecycle.ModeDelete, 10*time.Millisecond)
+	worker.Start()
+	defer worker.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for visible(t, db, 321) {
+		if time.Now().After(deadline) {
+			t.Fatal("the background loop did not remove the server")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,71 @@
+// Package logger настраивает структурированный журнал slog с уровнями и скрытием секретов.
+// После Setup стандартный пакет log тоже пишет через этот обработчик
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Redacted значение, которым заменяются секреты
+const Redacted = "[REDACTED]"
+
+// secretKeys ключи атрибутов, значения которых никогда не попадают в журнал
+var secretKeys = []string{"password", "passwd", "secret", "token", "authorization", "hash", "credential"}
+
+// IsSecretKey сообщает, что атрибут с таким ключом содержит секрет
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLevel разбирает уровень: debug, info, warn или error
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// New создаёт журнал с уровнем level в формате text или json
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if IsSecretKey(attr.Key) {
+				return slog.String(attr.Key, Redacted)
+			}
+			return attr
+		},
+	}
+
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected text or json", format)
+	}
+}
+
+// Setup делает журнал журналом по умолчанию для slog и log
+func Setup(level slog.Level, format string) error {
+	logger, err := New(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+	return nil
+}
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestIsSecretKey(t *testing.T) {
+	tests := map[string]bool{
+		"password":      true,
+		"new_password":  true,
+		"Authorization": true,
+		"password_hash": true,
+		"admin_token":   true,
+		"client_secret": true,
+		"username":      false,
+		"source_ip":     false,
+		"reason":        false,
+	}
+	for key, want := range tests {
+		if got := IsSecretKey(key); got != want {
+			t.Errorf("IsSecretKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestNewRedactsSecrets(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, slog.LevelInfo, format)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			logger.Info("authentication failed", "username", "test", "password", "hunter2",
+				"Authorization", "Basic dGVzdDpodW50ZXIy", slog.Group("user", "password_hash", "$2a$10$abc"))
+			logger.Debug("below the level", "username", "hidden")
+
+			out := buf.String()
+			for _, secret := range []string{"hunter2", "dGVzdDpodW50ZXIy", "$2a$10$abc", "hidden"} {
+				if strings.Contains(out, secret) {
+					t.Errorf("%q leaked: %s", secret, out)
+				}
+			}
+			if !strings.Contains(out, "test") || strings.Count(out, Redacted) != 3 {
+				t.Errorf("output = %s, want the username and three redacted values", out)
+			}
+		})
+	}
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	for value, want := range map[string]slog.Level{"debug": slog.LevelDebug, "info": slog.LevelInfo, "WARN": slog.LevelWarn, "error": slog.LevelError} {
+		if level, err := ParseLevel(value); err != nil || level != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", value, level, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil || !strings.Contains(err.Error(), `unknown log level "verbose"`) {
+		t.Errorf("ParseLevel(verbose) = %v", err)
+	}
+	if _, err := New(&bytes.Buffer{}, slog.LevelInfo, "xml"); err == nil || !strings.Contains(err.Error(), `unknown log format "xml"`) {
+		t.Errorf("New(xml) = %v", err)
+	}
+}
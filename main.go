@@ -1,63 +1,244 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
-	"net/http"
 
+	"hetzner-api-emulator/cassette"
+	"hetzner-api-emulator/clock"
 	"hetzner-api-emulator/config"
 	"hetzner-api-emulator/database"
-	"hetzner-api-emulator/models"
+	"hetzner-api-emulator/fixtures"
+	"hetzner-api-emulator/lifecycle"
+	"hetzner-api-emulator/logger"
 	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+	"hetzner-api-emulator/openapi"
 	"hetzner-api-emulator/routes" // Правильный импорт пакета routes
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func main() {
-	// Добавляем флаг для миграции
-	migrateFlag := flag.Bool("migration", false, "Run migrations")
-	flag.Parse()
-	cfg := config.LoadConfig()
+// runServe запускает эмулятор: команда serve, она же команда по умолчанию
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	// Флаг -migration оставлен для совместимости, это то же, что migrate up; остальные флаги регистрирует пакет config
+	migrateFlag := fs.Bool("migration", false, "Run migrations (same as the migrate up command)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: hetzner-api-emulator serve [flags]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "A persistent database (PostgreSQL, MySQL or an SQLite file) needs migrate up before the first start,")
+		fmt.Fprintln(fs.Output(), "or -auto-migrate=true to apply pending migrations at startup. An in-memory SQLite database is migrated automatically.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+
+	// Конфигурация: значения по умолчанию < YAML-файл < переменные окружения < флаги
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return configError(err)
+	}
+
+	// Структурированный журнал с уровнями; пароли и токены в нём скрываются
+	if err := logger.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	if cfg.File != "" {
+		log.Printf("Configuration loaded from %s", cfg.File)
+	}
+
+	// Режимы записи и воспроизведения не используют базу данных
+	if cfg.Mode != "emulate" {
+		return runCassette(cfg)
+	}
+
+	// Инициализируем подключение к базе данных
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("database connection error: %w", err)
+	}
+
 	// Если флаг миграции установлен, выполняем миграции и выходим
 	if *migrateFlag {
+		if _, err := migrations.Up(db, 0); err != nil {
+			return fmt.Errorf("migrations failed: %w", err)
+		}
+		log.Println("Migrations completed successfully")
+		return nil
+	}
 
-		// Инициализируем подключение к базе данных
-		database.ConnectDatabase()
+	// Стоимость bcrypt для паролей из фикстур и административного API
+	if err := models.SetBcryptCost(cfg.BcryptCost); err != nil {
+		return fmt.Errorf("invalid bcrypt_cost: %w", err)
+	}
 
-		// Получаем соединение с БД
-		db := database.GetDB()
+	// База в памяти пуста при каждом запуске, поэтому мигрируем её сразу, как и с -auto-migrate=true.
+	// Постоянную базу без схемы не запускаем: иначе эмулятор упал бы на первом запросе
+	if cfg.Database.IsInMemory() || cfg.AutoMigrate {
+		if _, err := migrations.Up(db, 0); err != nil {
+			return fmt.Errorf("migrations failed: %w", err)
+		}
+	} else if !db.Migrator().HasTable(&models.User{}) {
+		return errors.New("database has no schema: run migrate up first or start serve with -auto-migrate=true")
+	} else if pending, err := migrations.Pending(db); err != nil {
+		log.Printf("Failed to read schema version: %v", err)
+	} else if pending > 0 {
+		log.Printf("Schema is behind by %d migrations, run migrate up or start serve with -auto-migrate=true", pending)
+	}
 
-		// Запускаем миграции
-		models.Migrate(db)
+	// Загружаем фикстуры, если указан файл
+	if cfg.Fixtures != "" {
+		if err := fixtures.LoadFile(db, cfg.Fixtures); err != nil {
+			return fmt.Errorf("failed to load fixtures from %s: %w", cfg.Fixtures, err)
+		}
+		log.Printf("Fixtures loaded from %s", cfg.Fixtures)
+	}
 
-		log.Println("Migrations completed successfully")
-		return
+	// Проверяем загруженные серверы по каталогу продуктов и датацентров
+	if err := validateSeedData(db); err != nil {
+		return err
 	}
 
-	// Инициализируем подключение к базе данных
-	database.ConnectDatabase()
+	// Виртуальные часы: по умолчанию идут с реальным временем, управляются через /__admin/clock
+	clk := clock.New()
 
-	// Создаем роутер Gin
-	router := gin.Default()
+	// Фоновый обработчик жизненного цикла: отменённые серверы исчезают после даты отмены
+	startLifecycle(db, clk, cfg)
+
+	// Правила внедрения сбоев: из файла при старте, дальше через /__admin/faults
+	faults := middlewares.NewFaultInjector()
+	if cfg.FaultsFile != "" {
+		if err := faults.LoadFile(cfg.FaultsFile); err != nil {
+			return fmt.Errorf("failed to load fault rules: %w", err)
+		}
+	}
+
+	// Квоты запросов по группам маршрутов, как в Robot
+	limiter := middlewares.NewRateLimiter(clk, cfg.RateLimits)
+
+	// Блокировка IP после неудачных входов; настраивается и во время работы через /__admin/lockout
+	lockout := middlewares.NewLoginLockout(clk, cfg.LoginLockout)
+
+	// Журнал аутентифицированных запросов для /__admin/requests
+	journal := middlewares.NewJournal(clk, cfg.JournalSize)
+
+	// Журнал аудита аутентификации: по умолчанию только отказы, старые записи удаляются
+	audit := middlewares.NewAuthAudit(db, clk, cfg.AuthAudit)
 
-	// Обработчик для несуществующих маршрутов
-	router.NoRoute(func(c *gin.Context) {
-		middlewares.RespondWithError(c, http.StatusNotFound, "ROUTE_NOT_FOUND", "Route not found")
-	})
+	env := &routes.Env{
+		DB:      db,
+		DBType:  cfg.Database.Connection,
+		Clock:   clk,
+		Faults:  faults,
+		Limits:  limiter,
+		Journal: journal,
+		Audit:   audit,
+		Lockout: lockout,
 
-	// Подключаем обработчик ошибок
-	router.Use(middlewares.ErrorHandler())
+		TrustedProxies: cfg.TrustedProxies,
+	}
+
+	// Кэш проверенных паролей, чтобы не запускать bcrypt на каждый запрос
+	if cfg.AuthCacheTTL > 0 && cfg.AuthCacheSize > 0 {
+		env.AuthCache = middlewares.NewCredentialCache(cfg.AuthCacheTTL, cfg.AuthCacheSize)
+	}
+
+	// Строгий режим: неизвестные, некорректные и отсутствующие параметры отклоняются как INVALID_INPUT
+	if cfg.StrictMode {
+		validator, err := openapi.NewValidator()
+		if err != nil {
+			return fmt.Errorf("failed to load OpenAPI spec: %w", err)
+		}
+		env.Strict = validator
+	}
 
-	// Добавляем middleware авторизации с указанием типа базы данных
-	authorized := router.Group("/", middlewares.DBAuthMiddleware(database.GetDB()))
+	// Создаем роутер Gin со всеми маршрутами Robot API
+	router := routes.NewRouter(env)
 
-	// Регистрируем все маршруты через RegisterAllRoutes
-	routes.RegisterAllRoutes(authorized, database.GetDB(), cfg.DBType) // Используем правильный вызов из пакета routes
+	// Административный API: на отдельном порту или на основном за токеном
+	setupAdmin(router, env, cfg)
 
 	// Запускаем сервер
 	addr := cfg.Host + ":" + cfg.Port
 	log.Printf("Starting server at %s...", addr)
 	if err := router.Run(addr); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+		return fmt.Errorf("server failed to start: %w", err)
+	}
+	return nil
+}
+
+// runCassette запускает прокси с записью в кассету или воспроизведение кассеты
+func runCassette(cfg *config.Config) error {
+	router := gin.Default()
+	upstream, cassettePath := cfg.Upstream, cfg.Cassette
+
+	switch cfg.Mode {
+	case "record":
+		recorder, err := cassette.NewRecorder(upstream, cassettePath)
+		if err != nil {
+			return fmt.Errorf("failed to open cassette %s: %w", cassettePath, err)
+		}
+		defer recorder.Close()
+		router.NoRoute(recorder.Handle)
+		log.Printf("Recording requests to %s into %s", upstream, cassettePath)
+	case "replay":
+		replayer, err := cassette.NewReplayer(cassettePath)
+		if err != nil {
+			return fmt.Errorf("failed to load cassette %s: %w", cassettePath, err)
+		}
+		router.NoRoute(replayer.Handle)
+		log.Printf("Replaying responses from %s", cassettePath)
+	}
+
+	addr := cfg.Host + ":" + cfg.Port
+	log.Printf("Starting server at %s...", addr)
+	if err := router.Run(addr); err != nil {
+		return fmt.Errorf("server failed to start: %w", err)
+	}
+	return nil
+}
+
+// startLifecycle запускает фоновую обработку дат отмены и подписывает её на перестановку часов
+func startLifecycle(db *gorm.DB, clk *clock.Virtual, cfg *config.Config) {
+	worker := lifecycle.NewWorker(db, clk, cfg.LifecycleMode, cfg.LifecycleInterval)
+	worker.Attach(clk)
+	worker.Start()
+}
+
+// validateSeedData проверяет серверы по каталогу продуктов и не даёт запуститься с несовместимыми данными
+func validateSeedData(db *gorm.DB) error {
+	problems, err := models.ValidateServers(db)
+	if err != nil {
+		return fmt.Errorf("failed to validate servers against catalog: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("servers do not match the product catalog, fix or remove them:\n%w", errors.Join(problems...))
+	}
+	return nil
+}
+
+// setupAdmin подключает административный API /__admin. Если задан ADMIN_PORT, он слушает отдельный порт,
+// иначе монтируется на основной роутер и требует ADMIN_TOKEN
+func setupAdmin(router *gin.Engine, env *routes.Env, cfg *config.Config) {
+	if cfg.AdminPort != "" {
+		adminRouter := routes.NewAdminRouter(env, cfg.AdminToken)
+
+		addr := cfg.AdminHost + ":" + cfg.AdminPort
+		go func() {
+			log.Printf("Starting admin API at %s...", addr)
+			if err := adminRouter.Run(addr); err != nil {
+				log.Fatalf("Admin API failed to start: %v", err)
+			}
+		}()
+		return
+	}
+
+	if cfg.AdminToken == "" {
+		log.Println("Admin API disabled: set ADMIN_TOKEN or ADMIN_PORT to enable it")
+		return
 	}
+	routes.MountAdmin(router, env, cfg.AdminToken)
 }
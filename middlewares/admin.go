@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthMiddleware проверяет токен административного API в заголовке X-Admin-Token или Authorization: Bearer.
+// Пустой токен отключает проверку (допустимо, только если админка слушает отдельный порт)
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		provided := c.GetHeader("X-Admin-Token")
+		if provided == "" {
+			provided = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			RespondWithError(c, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or missing admin token")
+			return
+		}
+		c.Next()
+	}
+}
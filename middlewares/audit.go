@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"hetzner-api-emulator/clock"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pruneEvery через сколько новых записей аудита удаляются лишние и устаревшие
+const pruneEvery = 100
+
+// AuditSettings настройки журнала аудита аутентификации
+type AuditSettings struct {
+	LogSuccess bool          // Записывать и успешные входы; по умолчанию только отказы, иначе таблица растёт на каждый запрос
+	MaxEvents  int           // Сколько последних записей хранить; 0 — без ограничения
+	MaxAge     time.Duration // Сколько хранить записи по виртуальным часам; 0 — без ограничения
+}
+
+// AuthAudit записывает попытки аутентификации в таблицу auth_events и периодически удаляет старые записи
+type AuthAudit struct {
+	db       *gorm.DB
+	clock    clock.Clock
+	settings AuditSettings
+
+	mu      sync.Mutex
+	written int // Записей с последней очистки
+}
+
+// NewAuthAudit создаёт журнал аудита с заданными настройками
+func NewAuthAudit(db *gorm.DB, clk clock.Clock, settings AuditSettings) *AuthAudit {
+	return &AuthAudit{db: db, clock: clk, settings: settings}
+}
+
+// Record сохраняет запись аудита; успешные входы только при LogSuccess. Ошибка записи не мешает обработке запроса
+func (a *AuthAudit) Record(c *gin.Context, event models.AuthEvent) {
+	if a == nil {
+		return
+	}
+	if event.Result == models.AuthResultSuccess && !a.settings.LogSuccess {
+		return
+	}
+
+	event.Time = a.clock.Now().UTC()
+	event.SourceIP = c.ClientIP()
+	event.Method = c.Request.Method
+	event.Path = c.Request.URL.Path
+	if err := a.db.Create(&event).Error; err != nil {
+		slog.Error("failed to record auth event", "username", event.Username, "error", err)
+		return
+	}
+
+	a.mu.Lock()
+	a.written++
+	prune := a.written >= pruneEvery
+	if prune {
+		a.written = 0
+	}
+	a.mu.Unlock()
+	if prune {
+		a.Prune()
+	}
+}
+
+// Prune удаляет записи сверх MaxEvents и старше MaxAge
+func (a *AuthAudit) Prune() {
+	var before time.Time
+	if a.settings.MaxAge > 0 {
+		before = a.clock.Now().UTC().Add(-a.settings.MaxAge)
+	}
+	deleted, err := models.PruneAuthEvents(a.db, a.settings.MaxEvents, before)
+	if err != nil {
+		slog.Error("failed to prune auth events", "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Debug("auth events pruned", "deleted", deleted)
+	}
+}
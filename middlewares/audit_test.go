@@ -0,0 +1,127 @@
+package middlewares_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/clock"
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newTestDB создаёт базу SQLite в памяти с актуальной схемой
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatalf("migrations.Up: %v", err)
+	}
+	return db
+}
+
+func newTestContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/server", nil)
+	return c
+}
+
+func countEvents(t *testing.T, db *gorm.DB, result string) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.AuthEvent{}).Where("result = ?", result).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestAuthAuditRecord(t *testing.T) {
+	success := models.AuthEvent{Username: "test", UserID: 1, Result: models.AuthResultSuccess, Reason: models.AuthReasonOK}
+	failure := models.AuthEvent{Username: "test", Result: models.AuthResultFailure, Reason: models.AuthReasonWrongPassword}
+
+	tests := []struct {
+		name        string
+		settings    middlewares.AuditSettings
+		wantSuccess int64
+	}{
+		{"failures only by default", middlewares.AuditSettings{}, 0},
+		{"successes on request", middlewares.AuditSettings{LogSuccess: true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			audit := middlewares.NewAuthAudit(db, clock.New(), tt.settings)
+			audit.Record(newTestContext(), success)
+			audit.Record(newTestContext(), failure)
+
+			if got := countEvents(t, db, models.AuthResultSuccess); got != tt.wantSuccess {
+				t.Errorf("success events = %d, want %d", got, tt.wantSuccess)
+			}
+			if got := countEvents(t, db, models.AuthResultFailure); got != 1 {
+				t.Errorf("failure events = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestAuthAuditNilIsNoop(t *testing.T) {
+	var audit *middlewares.AuthAudit
+	audit.Record(newTestContext(), models.AuthEvent{Result: models.AuthResultFailure})
+}
+
+func TestAuthAuditPrune(t *testing.T) {
+	failure := models.AuthEvent{Username: "test", Result: models.AuthResultFailure, Reason: models.AuthReasonUnknownUser}
+
+	t.Run("by count", func(t *testing.T) {
+		db := newTestDB(t)
+		audit := middlewares.NewAuthAudit(db, clock.New(), middlewares.AuditSettings{MaxEvents: 5})
+		for i := 0; i < 12; i++ {
+			audit.Record(newTestContext(), failure)
+		}
+		audit.Prune()
+
+		var ids []int64
+		if err := db.Model(&models.AuthEvent{}).Order("id").Pluck("id", &ids).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 5 || ids[0] != 8 {
+			t.Errorf("kept ids %v, want the newest 5 (8..12)", ids)
+		}
+	})
+
+	t.Run("by age", func(t *testing.T) {
+		db := newTestDB(t)
+		clk := clock.New()
+		clk.Freeze()
+		audit := middlewares.NewAuthAudit(db, clk, middlewares.AuditSettings{MaxAge: time.Hour})
+		for i := 0; i < 3; i++ {
+			audit.Record(newTestContext(), failure)
+		}
+		clk.Advance(2 * time.Hour)
+		audit.Record(newTestContext(), failure)
+		audit.Prune()
+
+		if got := countEvents(t, db, models.AuthResultFailure); got != 1 {
+			t.Errorf("events after prune = %d, want 1", got)
+		}
+	})
+
+	t.Run("automatically", func(t *testing.T) {
+		db := newTestDB(t)
+		audit := middlewares.NewAuthAudit(db, clock.New(), middlewares.AuditSettings{MaxEvents: 10})
+		for i := 0; i < 100; i++ {
+			audit.Record(newTestContext(), failure)
+		}
+		if got := countEvents(t, db, models.AuthResultFailure); got != 10 {
+			t.Errorf("events = %d, want 10 after the periodic prune", got)
+		}
+	})
+}
@@ -3,95 +3,127 @@ package middlewares
 import (
 	"encoding/base64"
 	"hetzner-api-emulator/models"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// DBAuthMiddleware проверяет базовую авторизацию с использованием базы данных
-func DBAuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+// DBAuthMiddleware проверяет базовую авторизацию с использованием базы данных.
+// Если audit не nil, отказы (и, если включено, успешные входы) записываются в журнал аудита (models.AuthEvent).
+// Если cache не nil, повторная проверка того же пароля обходится без bcrypt.
+// Если lockout не nil, неверные пароли считаются по IP источника и временно блокируют адрес
+func DBAuthMiddleware(db *gorm.DB, audit *AuthAudit, cache *CredentialCache, lockout *LoginLockout) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Пропускаем авторизацию для маршрута регистрации
-		if c.Request.URL.Path == "/register" {
-			c.Next()
-			return
+		// Заблокированный после неудачных входов адрес не проверяется дальше
+		if lockout != nil {
+			if until, blocked := lockout.Blocked(c.ClientIP()); blocked {
+				rejectAuthWith(c, audit, "", models.AuthReasonIPBlocked, NewRobotError(http.StatusForbidden, "IP_BLOCKED",
+					"Too many failed login attempts, IP address blocked until "+until.UTC().Format(time.RFC3339)))
+				return
+			}
 		}
 
 		// Получаем значение авторизации из заголовка
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			log.Println("Authorization header is missing")
-			SetError(c, "AUTH_HEADER_MISSING", http.StatusUnauthorized)
-			RespondWithError(c, http.StatusUnauthorized, "AUTH_HEADER_MISSING", "Authorization header required")
-			c.Abort()
+			rejectAuth(c, audit, "", models.AuthReasonHeaderMissing, "Authorization header required")
 			return
 		}
 
 		// Проверяем формат заголовка (Basic ...)
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Basic" {
-			log.Println("Invalid authorization format")
-			SetError(c, "INVALID_AUTH_FORMAT", http.StatusUnauthorized)
-			RespondWithError(c, http.StatusUnauthorized, "INVALID_AUTH_FORMAT", "Invalid authorization format")
-			c.Abort()
+			rejectAuth(c, audit, "", models.AuthReasonInvalidFormat, "Invalid authorization format")
 			return
 		}
 
 		// Декодируем Base64
 		decoded, err := base64.StdEncoding.DecodeString(parts[1])
 		if err != nil {
-			log.Printf("Base64 decoding error: %v", err)
-			SetError(c, "INVALID_BASE64_ENCODING", http.StatusUnauthorized)
-			RespondWithError(c, http.StatusUnauthorized, "INVALID_BASE64_ENCODING", "Invalid Base64 encoding")
-			c.Abort()
+			rejectAuth(c, audit, "", models.AuthReasonInvalidBase64, "Invalid Base64 encoding")
 			return
 		}
 
 		// Разделяем username и password
 		credentials := strings.SplitN(string(decoded), ":", 2)
 		if len(credentials) != 2 {
-			log.Println("Invalid username or password format")
-			SetError(c, "INVALID_USERNAME_PASSWORD_FORMAT", http.StatusUnauthorized)
-			RespondWithError(c, http.StatusUnauthorized, "INVALID_USERNAME_PASSWORD_FORMAT", "Invalid username or password format")
-			c.Abort()
+			rejectAuth(c, audit, "", models.AuthReasonInvalidCredFormat, "Invalid username or password format")
 			return
 		}
 		username := credentials[0]
 		password := strings.TrimSpace(credentials[1])
 
-		// Логируем полученные данные для отладки
-		log.Printf("Decoded credentials: username=%s, password=%s", username, password)
-
 		// Поиск пользователя в базе данных
 		var user models.User
+		if err := db.Where("username = ?", username).Limit(1).Find(&user).Error; err != nil {
+			slog.Error("auth user lookup failed", "username", username, "error", err)
+			rejectAuth(c, audit, username, models.AuthReasonDatabaseError, "Invalid username or password")
+			return
+		}
+		if user.ID == 0 {
+			recordLoginFailure(lockout, c)
+			rejectAuth(c, audit, username, models.AuthReasonUnknownUser, "Invalid username or password")
+			return
+		}
 
-		err = db.Where("username = ?", username).First(&user).Error
-		if err != nil {
-			log.Println("User not found or database error")
-			SetError(c, "INVALID_USERNAME_PASSWORD", http.StatusUnauthorized)
-			RespondWithError(c, http.StatusUnauthorized, "INVALID_USERNAME_PASSWORD", "Invalid username or password")
-			c.Abort()
+		// Отключённая учётная запись не проходит аутентификацию, даже с верным паролем
+		if user.Disabled {
+			rejectAuth(c, audit, username, models.AuthReasonUserDisabled, "Invalid username or password")
 			return
 		}
 
-		// Проверяем пароль
-		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-		if err != nil {
-			log.Printf("Password verification failed: expected hash=%s, got password=%s", user.Password, password)
-			log.Printf("Error: %v", err)
-			SetError(c, "INVALID_USERNAME_PASSWORD", http.StatusUnauthorized)
-			RespondWithError(c, http.StatusUnauthorized, "INVALID_USERNAME_PASSWORD", "Invalid username or password")
-			c.Abort()
+		// Проверяем пароль: сначала по кэшу, затем bcrypt
+		if cache == nil || !cache.Verify(username, password, user.Password) {
+			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+				recordLoginFailure(lockout, c)
+				rejectAuth(c, audit, username, models.AuthReasonWrongPassword, "Invalid username or password")
+				return
+			}
+			if cache != nil {
+				cache.Remember(username, password, user.Password)
+			}
+		}
+
+		// Доступ только с разрешённых адресов, если список задан. Проверяется после пароля:
+		// иначе 403 без пароля подтверждал бы, что пользователь существует, и обходил счётчик блокировки
+		if !user.AllowsIP(c.ClientIP()) {
+			rejectAuthWith(c, audit, username, models.AuthReasonIPNotAllowed, NewRobotError(http.StatusForbidden, "FORBIDDEN",
+				"Access from IP address "+c.ClientIP()+" is not allowed"))
 			return
 		}
 
 		// Аутентификация прошла успешно, сохраняем user_id в контексте
-		log.Printf("Authentication successful for user: %s", username)
+		if lockout != nil {
+			lockout.RecordSuccess(c.ClientIP())
+		}
+		slog.Debug("authentication succeeded", "username", username, "user_id", user.ID, "source_ip", c.ClientIP())
+		audit.Record(c, models.AuthEvent{Username: username, UserID: user.ID, Result: models.AuthResultSuccess, Reason: models.AuthReasonOK})
 		c.Set("user_id", user.ID) // Добавляем user_id в контекст
+		c.Set("user_scopes", user.ScopeList())
 		c.Next()
 	}
 }
+
+// rejectAuth записывает неудачную попытку и отвечает 401 UNAUTHORIZED, как Robot. Причина отказа остаётся в журнале аудита
+func rejectAuth(c *gin.Context, audit *AuthAudit, username, reason, message string) {
+	rejectAuthWith(c, audit, username, reason, NewRobotError(http.StatusUnauthorized, "UNAUTHORIZED", message))
+}
+
+// rejectAuthWith записывает неудачную попытку и отвечает ошибкой err
+func rejectAuthWith(c *gin.Context, audit *AuthAudit, username, reason string, err *RobotError) {
+	slog.Info("authentication failed", "username", username, "source_ip", c.ClientIP(), "reason", reason)
+	audit.Record(c, models.AuthEvent{Username: username, Result: models.AuthResultFailure, Reason: reason})
+	RespondWithRobotError(c, err)
+}
+
+// recordLoginFailure учитывает неверные учётные данные в счётчике блокировки
+func recordLoginFailure(lockout *LoginLockout, c *gin.Context) {
+	if lockout != nil && lockout.RecordFailure(c.ClientIP()) {
+		slog.Warn("IP address blocked after failed logins", "source_ip", c.ClientIP())
+	}
+}
@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// CredentialCache запоминает успешно проверенные пары логин/пароль, чтобы не запускать bcrypt на каждый запрос.
+// Хранится не пароль, а HMAC от пароля и текущего bcrypt-хеша пользователя со случайным ключом процесса:
+// после смены пароля хеш в базе меняется и старая запись перестаёт совпадать.
+// Размер ограничен, при переполнении вытесняется давно не использованная запись
+type CredentialCache struct {
+	mu      sync.Mutex
+	key     []byte
+	ttl     time.Duration
+	max     int
+	entries map[string]*list.Element
+	order   *list.List // Начало — недавно использованные
+	hits    int64
+	misses  int64
+	now     func() time.Time
+}
+
+type credentialEntry struct {
+	username string
+	digest   []byte
+	expires  time.Time
+}
+
+// CredentialCacheStats состояние кэша для административного API
+type CredentialCacheStats struct {
+	Size    int    `json:"size"`
+	MaxSize int    `json:"max_size"`
+	TTL     string `json:"ttl"`
+	Hits    int64  `json:"hits"`
+	Misses  int64  `json:"misses"`
+}
+
+// NewCredentialCache создаёт кэш на max записей со временем жизни ttl
+func NewCredentialCache(ttl time.Duration, max int) *CredentialCache {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return &CredentialCache{
+		key:     key,
+		ttl:     ttl,
+		max:     max,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// digest HMAC от пароля, привязанный к bcrypt-хешу пользователя
+func (c *CredentialCache) digest(username, password, passwordHash string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(passwordHash))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+// Verify сообщает, что пароль уже был проверен для пользователя с этим хешем и запись не устарела
+func (c *CredentialCache) Verify(username, password, passwordHash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[username]
+	if !ok {
+		c.misses++
+		return false
+	}
+	entry := element.Value.(*credentialEntry)
+	if c.now().After(entry.expires) {
+		c.remove(element)
+		c.misses++
+		return false
+	}
+	if !hmac.Equal(entry.digest, c.digest(username, password, passwordHash)) {
+		c.misses++
+		return false
+	}
+	c.order.MoveToFront(element)
+	c.hits++
+	return true
+}
+
+// Remember запоминает пароль, успешно проверенный bcrypt
+func (c *CredentialCache) Remember(username, password, passwordHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &credentialEntry{
+		username: username,
+		digest:   c.digest(username, password, passwordHash),
+		expires:  c.now().Add(c.ttl),
+	}
+	if element, ok := c.entries[username]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[username] = c.order.PushFront(entry)
+	for c.order.Len() > c.max {
+		c.remove(c.order.Back())
+	}
+}
+
+// Invalidate удаляет запись пользователя, например после смены пароля или удаления
+func (c *CredentialCache) Invalidate(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[username]; ok {
+		c.remove(element)
+	}
+}
+
+// Clear очищает кэш
+func (c *CredentialCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+	c.hits, c.misses = 0, 0
+}
+
+// Stats возвращает размер кэша и число попаданий
+func (c *CredentialCache) Stats() CredentialCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CredentialCacheStats{
+		Size:    c.order.Len(),
+		MaxSize: c.max,
+		TTL:     c.ttl.String(),
+		Hits:    c.hits,
+		Misses:  c.misses,
+	}
+}
+
+func (c *CredentialCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*credentialEntry).username)
+}
@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestCredentialCache создаёт кэш с управляемым временем
+func newTestCredentialCache(ttl time.Duration, max int) (*CredentialCache, *time.Time) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewCredentialCache(ttl, max)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestCredentialCacheVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		advance  time.Duration
+		want     bool
+	}{
+		{"same password and hash", "secret", "hash-1", 0, true},
+		{"wrong password", "guess", "hash-1", 0, false},
+		{"password hash changed", "secret", "hash-2", 0, false},
+		{"before ttl", "secret", "hash-1", time.Minute - time.Second, true},
+		{"after ttl", "secret", "hash-1", time.Minute + time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, now := newTestCredentialCache(time.Minute, 10)
+			cache.Remember("test", "secret", "hash-1")
+			*now = now.Add(tt.advance)
+
+			if got := cache.Verify("test", tt.password, tt.hash); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialCacheExpiredEntryIsRemoved(t *testing.T) {
+	cache, now := newTestCredentialCache(time.Minute, 10)
+	cache.Remember("test", "secret", "hash-1")
+	*now = now.Add(2 * time.Minute)
+
+	cache.Verify("test", "secret", "hash-1")
+	if size := cache.Stats().Size; size != 0 {
+		t.Errorf("size = %d after expiry, want 0", size)
+	}
+}
+
+func TestCredentialCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, _ := newTestCredentialCache(time.Minute, 2)
+	cache.Remember("a", "pa", "ha")
+	cache.Remember("b", "pb", "hb")
+	// Обращение к a делает вытесняемой запись b
+	if !cache.Verify("a", "pa", "ha") {
+		t.Fatal("a is not cached")
+	}
+	cache.Remember("c", "pc", "hc")
+
+	tests := []struct {
+		username, password, hash string
+		want                     bool
+	}{
+		{"a", "pa", "ha", true},
+		{"b", "pb", "hb", false},
+		{"c", "pc", "hc", true},
+	}
+	for _, tt := range tests {
+		if got := cache.Verify(tt.username, tt.password, tt.hash); got != tt.want {
+			t.Errorf("Verify(%s) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+	if size := cache.Stats().Size; size != 2 {
+		t.Errorf("size = %d, want 2", size)
+	}
+}
+
+func TestCredentialCacheRememberReplacesEntry(t *testing.T) {
+	cache, _ := newTestCredentialCache(time.Minute, 10)
+	cache.Remember("test", "old", "hash-1")
+	cache.Remember("test", "new", "hash-2")
+
+	if cache.Verify("test", "old", "hash-1") {
+		t.Error("old password still accepted")
+	}
+	if !cache.Verify("test", "new", "hash-2") {
+		t.Error("new password not accepted")
+	}
+	if size := cache.Stats().Size; size != 1 {
+		t.Errorf("size = %d, want 1", size)
+	}
+}
+
+func TestCredentialCacheInvalidateAndClear(t *testing.T) {
+	cache, _ := newTestCredentialCache(time.Minute, 10)
+	cache.Remember("a", "pa", "ha")
+	cache.Remember("b", "pb", "hb")
+
+	cache.Invalidate("a")
+	if cache.Verify("a", "pa", "ha") {
+		t.Error("a is still cached after Invalidate")
+	}
+	if !cache.Verify("b", "pb", "hb") {
+		t.Error("Invalidate removed b")
+	}
+
+	cache.Clear()
+	stats := cache.Stats()
+	if stats.Size != 0 || stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("stats after Clear = %+v, want all zero", stats)
+	}
+	if cache.Verify("b", "pb", "hb") {
+		t.Error("b is still cached after Clear")
+	}
+}
@@ -1,10 +1,82 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
 	"github.com/gin-gonic/gin"
 )
 
+// RobotError ошибка в формате Robot. Необязательные поля попадают в ответ, только если заданы:
+// missing и invalid для INVALID_INPUT, max_request и interval для RATE_LIMIT_EXCEEDED
+type RobotError struct {
+	Status     int      `json:"status"`
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	Missing    []string `json:"missing,omitempty"`
+	Invalid    []string `json:"invalid,omitempty"`
+	MaxRequest int      `json:"max_request,omitempty"`
+	Interval   int      `json:"interval,omitempty"`
+}
+
+// Error реализует интерфейс error
+func (e *RobotError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// NewRobotError создаёт ошибку только со статусом, кодом и сообщением
+func NewRobotError(status int, code string, message string) *RobotError {
+	return &RobotError{Status: status, Code: code, Message: message}
+}
+
+// NewInvalidInput создаёт ошибку INVALID_INPUT со списками отсутствующих и некорректных параметров
+func NewInvalidInput(missing, invalid []string) *RobotError {
+	if missing == nil {
+		missing = []string{}
+	}
+	if invalid == nil {
+		invalid = []string{}
+	}
+	return &RobotError{
+		Status:  http.StatusBadRequest,
+		Code:    "INVALID_INPUT",
+		Message: "invalid input",
+		Missing: missing,
+		Invalid: invalid,
+	}
+}
+
+// NewRateLimitExceeded создаёт ошибку RATE_LIMIT_EXCEEDED с квотой, которая была превышена
+func NewRateLimitExceeded(maxRequest, interval int) *RobotError {
+	return &RobotError{
+		Status:     http.StatusForbidden,
+		Code:       "RATE_LIMIT_EXCEEDED",
+		Message:    "Rate limit exceeded",
+		MaxRequest: maxRequest,
+		Interval:   interval,
+	}
+}
+
+// details возвращает необязательные поля ошибки для ответа
+func (e *RobotError) details() gin.H {
+	details := gin.H{}
+	if e.Missing != nil || e.Invalid != nil {
+		missing, invalid := e.Missing, e.Invalid
+		if missing == nil {
+			missing = []string{}
+		}
+		if invalid == nil {
+			invalid = []string{}
+		}
+		details["missing"] = missing
+		details["invalid"] = invalid
+	}
+	if e.MaxRequest > 0 {
+		details["max_request"] = e.MaxRequest
+		details["interval"] = e.Interval
+	}
+	return details
+}
+
 // ErrorHandler middleware для форматирования ошибок
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -14,6 +86,13 @@ func ErrorHandler() gin.HandlerFunc {
 		if len(c.Errors) > 0 {
 			err := c.Errors.Last()
 
+			// Типизированная ошибка Robot несёт всё нужное сама
+			var robotErr *RobotError
+			if errors.As(err.Err, &robotErr) {
+				RespondWithRobotError(c, robotErr)
+				return
+			}
+
 			// Получаем значение кода ошибки и статуса из контекста
 			errorCode, exists := c.Get("errorCode")
 			if !exists {
@@ -33,22 +112,37 @@ func ErrorHandler() gin.HandlerFunc {
 
 // RespondWithError отправляет JSON-ответ с информацией об ошибке
 func RespondWithError(c *gin.Context, status int, code string, message string) {
+	RespondWithErrorDetails(c, status, code, message, nil)
+}
+
+// RespondWithErrorDetails отправляет ошибку с дополнительными полями Robot (например, max_request и interval)
+func RespondWithErrorDetails(c *gin.Context, status int, code string, message string, details gin.H) {
 	// Устанавливаем статус ответа
 	if status == 0 {
 		status = http.StatusInternalServerError
 	}
 
 	// Формируем ответ в нужном формате
+	body := gin.H{
+		"status":  status,
+		"code":    code,
+		"message": message,
+	}
+	for key, value := range details {
+		body[key] = value
+	}
 	c.JSON(status, gin.H{
-		"error": gin.H{
-			"status":  status,
-			"code":    code,
-			"message": message,
-		},
+		"error": body,
 	})
 	c.Abort()
 }
 
+// RespondWithRobotError отправляет типизированную ошибку Robot вместе с её необязательными полями
+func RespondWithRobotError(c *gin.Context, err *RobotError) {
+	SetError(c, err.Code, err.Status)
+	RespondWithErrorDetails(c, err.Status, err.Code, err.Message, err.details())
+}
+
 // SetError добавляет код ошибки и статус в контекст
 func SetError(c *gin.Context, code string, status int) {
 	c.Set("errorCode", code)
@@ -0,0 +1,317 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Действия правил внедрения сбоев
+const (
+	FaultActionError    = "error"    // Ответить ошибкой Robot с заданным статусом и кодом
+	FaultActionLatency  = "latency"  // Только задержать ответ
+	FaultActionDrop     = "drop"     // Закрыть соединение, ничего не ответив
+	FaultActionTruncate = "truncate" // Отдать часть тела ответа и закрыть соединение
+)
+
+// Ключи контекста, через которые Middleware сообщает журналу о внедрённом сбое
+const (
+	faultActionKey = "faultAction"
+	faultStatusKey = "faultStatus" // Статус из строки ответа, если соединение перехвачено; 0 — ответа не было
+)
+
+// FaultRule правило внедрения сбоя. Пустые method, path и user_id совпадают с любым запросом
+type FaultRule struct {
+	Method        string   `json:"method,omitempty"`
+	Path          string   `json:"path,omitempty"` // Шаблон path.Match, например /server/*/cancellation
+	UserID        int      `json:"user_id,omitempty"`
+	Probability   *float64 `json:"probability,omitempty"` // От 0 до 1, по умолчанию 1
+	Action        string   `json:"action"`
+	Status        int      `json:"status,omitempty"`
+	Code          string   `json:"code,omitempty"`
+	Message       string   `json:"message,omitempty"`
+	Latency       string   `json:"latency,omitempty"`        // Задержка перед обработкой, например 500ms
+	TruncateBytes int      `json:"truncate_bytes,omitempty"` // Сколько байт тела отдать, по умолчанию половину
+	Times         int      `json:"times,omitempty"`          // Сколько раз сработать, 0 — без ограничений
+	Fired         int      `json:"fired"`                    // Сколько раз правило уже сработало
+}
+
+// Validate проверяет правило и заполняет значения по умолчанию
+func (r *FaultRule) Validate() error {
+	switch r.Action {
+	case FaultActionError:
+		if r.Status == 0 {
+			r.Status = http.StatusInternalServerError
+		}
+		if r.Code == "" {
+			r.Code = "INTERNAL_ERROR"
+		}
+		if r.Message == "" {
+			r.Message = "Injected fault"
+		}
+	case FaultActionLatency:
+		if r.Latency == "" {
+			return fmt.Errorf("latency is required for action %q", r.Action)
+		}
+	case FaultActionDrop, FaultActionTruncate:
+	default:
+		return fmt.Errorf("unknown action %q, expected error, latency, drop or truncate", r.Action)
+	}
+
+	if r.Latency != "" {
+		if _, err := time.ParseDuration(r.Latency); err != nil {
+			return fmt.Errorf("invalid latency %q: %v", r.Latency, err)
+		}
+	}
+	if r.Path != "" {
+		if _, err := path.Match(r.Path, "/"); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %v", r.Path, err)
+		}
+	}
+	if r.Probability != nil && (*r.Probability < 0 || *r.Probability > 1) {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	r.Method = strings.ToUpper(r.Method)
+	r.Fired = 0
+	return nil
+}
+
+// matches проверяет, относится ли правило к запросу
+func (r *FaultRule) matches(c *gin.Context) bool {
+	if r.Times > 0 && r.Fired >= r.Times {
+		return false
+	}
+	if r.Method != "" && r.Method != "*" && r.Method != c.Request.Method {
+		return false
+	}
+	if r.Path != "" {
+		if ok, _ := path.Match(r.Path, c.Request.URL.Path); !ok {
+			return false
+		}
+	}
+	if r.UserID != 0 {
+		if userID, err := GetUserIDFromContext(c); err != nil || userID != r.UserID {
+			return false
+		}
+	}
+	return true
+}
+
+// FaultInjector хранит правила внедрения сбоев, которые можно менять во время работы
+type FaultInjector struct {
+	mu    sync.Mutex
+	rules []*FaultRule
+	rnd   *rand.Rand
+}
+
+// NewFaultInjector создаёт пустой набор правил
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// LoadFile заменяет правила правилами из JSON-файла (массив FaultRule)
+func (f *FaultInjector) LoadFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var rules []FaultRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("parse %s: %w", filename, err)
+	}
+	return f.SetRules(rules)
+}
+
+// SetRules заменяет все правила
+func (f *FaultInjector) SetRules(rules []FaultRule) error {
+	prepared := make([]*FaultRule, 0, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		prepared = append(prepared, &rule)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = prepared
+	return nil
+}
+
+// AddRule добавляет правило в конец списка
+func (f *FaultInjector) AddRule(rule FaultRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, &rule)
+	return nil
+}
+
+// Rules возвращает копию текущих правил со счётчиками срабатываний
+func (f *FaultInjector) Rules() []FaultRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make([]FaultRule, 0, len(f.rules))
+	for _, rule := range f.rules {
+		result = append(result, *rule)
+	}
+	return result
+}
+
+// Clear удаляет все правила
+func (f *FaultInjector) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = nil
+}
+
+// pick выбирает первое подходящее правило с учётом вероятности и отмечает срабатывание
+func (f *FaultInjector) pick(c *gin.Context) *FaultRule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rule := range f.rules {
+		if !rule.matches(c) {
+			continue
+		}
+		if rule.Probability != nil && f.rnd.Float64() >= *rule.Probability {
+			continue
+		}
+		rule.Fired++
+		fired := *rule
+		return &fired
+	}
+	return nil
+}
+
+// Middleware внедряет сбои по правилам; подключается после DBAuthMiddleware, чтобы правила могли учитывать user_id
+func (f *FaultInjector) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule := f.pick(c)
+		if rule == nil {
+			c.Next()
+			return
+		}
+
+		if rule.Latency != "" {
+			latency, _ := time.ParseDuration(rule.Latency)
+			time.Sleep(latency)
+		}
+
+		c.Set(faultActionKey, rule.Action)
+		switch rule.Action {
+		case FaultActionError:
+			SetError(c, rule.Code, rule.Status)
+			RespondWithError(c, rule.Status, rule.Code, rule.Message)
+		case FaultActionDrop:
+			dropConnection(c)
+		case FaultActionTruncate:
+			truncateResponse(c, rule.TruncateBytes)
+		default:
+			c.Next()
+		}
+	}
+}
+
+// hijack перехватывает соединение. Hijack в Gin паникует, если исходный ResponseWriter
+// его не поддерживает (HTTP/2, httptest.ResponseRecorder), поэтому поддержка проверяется заранее
+func hijack(w gin.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	if unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+		if _, ok := unwrapper.Unwrap().(http.Hijacker); !ok {
+			return nil, nil, errors.New("response writer does not support hijacking")
+		}
+	}
+	return w.Hijack()
+}
+
+// dropConnection закрывает соединение без ответа
+func dropConnection(c *gin.Context) {
+	c.Abort()
+	conn, _, err := hijack(c.Writer)
+	if err != nil {
+		// Соединение нельзя перехватить (например, HTTP/2) — отвечаем пустым 502
+		c.Status(http.StatusBadGateway)
+		return
+	}
+	c.Set(faultStatusKey, 0)
+	conn.Close()
+}
+
+// bufferedWriter накапливает ответ обработчика, чтобы потом отдать его частично
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// truncateResponse выполняет обработчик, отдаёт заголовки с полной длиной, но только часть тела, и рвёт соединение
+func truncateResponse(c *gin.Context, limit int) {
+	original := c.Writer
+	buffered := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+	c.Writer = buffered
+	c.Next()
+	c.Writer = original
+
+	body := buffered.body.Bytes()
+	if limit <= 0 || limit >= len(body) {
+		limit = len(body) / 2
+	}
+
+	conn, rw, err := hijack(original)
+	if err != nil {
+		original.WriteHeader(buffered.status)
+		original.Write(body[:limit])
+		return
+	}
+	defer conn.Close()
+	c.Set(faultStatusKey, buffered.status)
+
+	header := original.Header()
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	fmt.Fprintf(rw, "HTTP/1.1 %d %s\r\n", buffered.status, http.StatusText(buffered.status))
+	header.Write(rw)
+	rw.WriteString("\r\n")
+	rw.Write(body[:limit])
+	rw.Flush()
+}
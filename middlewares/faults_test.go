@@ -0,0 +1,253 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newFaultServer поднимает сервер с журналом и сбоями в том же порядке, что и в роутере
+func newFaultServer(t *testing.T, rule FaultRule) *Journal {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	journal := NewJournal(clock.New(), 0)
+	faults := NewFaultInjector()
+	if err := faults.AddRule(rule); err != nil {
+		t.Fatalf("AddRule: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/server", journal.Middleware(), faults.Middleware(), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"server": []string{"one", "two", "three"}})
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	if resp, err := http.Get(server.URL + "/server"); err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	return journal
+}
+
+func TestJournalRecordsInjectedFaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       FaultRule
+		wantStatus int
+	}{
+		{"drop", FaultRule{Action: FaultActionDrop}, 0},
+		{"truncate", FaultRule{Action: FaultActionTruncate, TruncateBytes: 5}, http.StatusCreated},
+		{"error", FaultRule{Action: FaultActionError, Status: http.StatusServiceUnavailable}, http.StatusServiceUnavailable},
+		{"latency", FaultRule{Action: FaultActionLatency, Latency: "1ms"}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := newFaultServer(t, tt.rule).Entries(JournalFilter{})
+			if len(entries) != 1 {
+				t.Fatalf("got %d journal entries, want 1", len(entries))
+			}
+			if entries[0].Fault != tt.rule.Action {
+				t.Errorf("fault = %q, want %q", entries[0].Fault, tt.rule.Action)
+			}
+			if entries[0].Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", entries[0].Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestJournalFilterByFault(t *testing.T) {
+	journal := newFaultServer(t, FaultRule{Action: FaultActionDrop})
+	if got := journal.Entries(JournalFilter{Fault: FaultActionDrop}); len(got) != 1 {
+		t.Errorf("fault=drop matched %d entries, want 1", len(got))
+	}
+	if got := journal.Entries(JournalFilter{Fault: FaultActionError}); len(got) != 0 {
+		t.Errorf("fault=error matched %d entries, want 0", len(got))
+	}
+}
+
+// newFaultRouter поднимает роутер с правилами сбоев; обработчик отвечает 201 и телом из трёх серверов
+func newFaultRouter(t *testing.T, rules ...FaultRule) (*gin.Engine, *FaultInjector) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	faults := NewFaultInjector()
+	if err := faults.SetRules(rules); err != nil {
+		t.Fatalf("SetRules: %v", err)
+	}
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"server": []string{"one", "two", "three"}})
+	}
+	router.GET("/server", faults.Middleware(), handler)
+	router.POST("/server/:server-number", faults.Middleware(), handler)
+	return router, faults
+}
+
+// record выполняет запрос без сети, через httptest.ResponseRecorder, который нельзя перехватить
+func record(router *gin.Engine, method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+const fullBody = `{"server":["one","two","three"]}`
+
+func TestFaultDrop(t *testing.T) {
+	router, _ := newFaultRouter(t, FaultRule{Action: FaultActionDrop, Times: 1})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	if resp, err := http.Get(server.URL + "/server"); err == nil {
+		resp.Body.Close()
+		t.Fatalf("dropped request got status %d, want a connection error", resp.StatusCode)
+	}
+	// Сервер продолжает работать после разорванного соединения
+	resp, err := http.Get(server.URL + "/server")
+	if err != nil {
+		t.Fatalf("request after the drop: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("request after the drop: status = %d, want 201", resp.StatusCode)
+	}
+}
+
+func TestFaultDropWithoutHijack(t *testing.T) {
+	router, _ := newFaultRouter(t, FaultRule{Action: FaultActionDrop})
+	if recorder := record(router, http.MethodGet, "/server"); recorder.Code != http.StatusBadGateway || recorder.Body.Len() != 0 {
+		t.Errorf("status = %d, body %q, want an empty 502", recorder.Code, recorder.Body)
+	}
+}
+
+func TestFaultTruncate(t *testing.T) {
+	router, _ := newFaultRouter(t, FaultRule{Action: FaultActionTruncate, TruncateBytes: 10})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/server")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated || resp.ContentLength != int64(len(fullBody)) {
+		t.Errorf("status = %d, Content-Length %d, want 201 and %d", resp.StatusCode, resp.ContentLength, len(fullBody))
+	}
+	if err == nil || string(body) != fullBody[:10] {
+		t.Errorf("body %q, err %v, want %q and an unexpected EOF", body, err, fullBody[:10])
+	}
+}
+
+func TestFaultTruncateWithoutHijack(t *testing.T) {
+	// Без truncate_bytes отдаётся половина тела
+	router, _ := newFaultRouter(t, FaultRule{Action: FaultActionTruncate})
+	recorder := record(router, http.MethodGet, "/server")
+	if recorder.Code != http.StatusCreated || recorder.Body.String() != fullBody[:len(fullBody)/2] {
+		t.Errorf("status = %d, body %q, want 201 and half of the body", recorder.Code, recorder.Body)
+	}
+}
+
+func TestFaultLatency(t *testing.T) {
+	// Задержка работает и отдельным действием, и вместе с ошибкой
+	router, _ := newFaultRouter(t,
+		FaultRule{Method: http.MethodPost, Action: FaultActionError, Status: http.StatusServiceUnavailable, Latency: "30ms"},
+		FaultRule{Action: FaultActionLatency, Latency: "30ms"},
+	)
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/server", http.StatusCreated},
+		{http.MethodPost, "/server/321", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		recorder := record(router, tt.method, tt.target)
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+			t.Errorf("%s %s: answered in %s, want at least 30ms", tt.method, tt.target, elapsed)
+		}
+		if recorder.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.target, recorder.Code, tt.want)
+		}
+	}
+}
+
+func TestFaultRuleTimes(t *testing.T) {
+	router, faults := newFaultRouter(t, FaultRule{Action: FaultActionError, Status: http.StatusServiceUnavailable, Times: 2})
+	codes := make([]int, 3)
+	for i := range codes {
+		codes[i] = record(router, http.MethodGet, "/server").Code
+	}
+	if codes[0] != http.StatusServiceUnavailable || codes[1] != http.StatusServiceUnavailable || codes[2] != http.StatusCreated {
+		t.Errorf("status codes = %v, want two faults and then 201", codes)
+	}
+	if rules := faults.Rules(); rules[0].Fired != 2 {
+		t.Errorf("fired = %d, want 2", rules[0].Fired)
+	}
+}
+
+func TestFaultRuleProbability(t *testing.T) {
+	never, always := 0.0, 1.0
+	tests := []struct {
+		probability *float64
+		want        int
+	}{
+		{&never, http.StatusCreated},
+		{&always, http.StatusServiceUnavailable},
+		{nil, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		router, faults := newFaultRouter(t, FaultRule{Action: FaultActionError, Status: http.StatusServiceUnavailable, Probability: tt.probability})
+		for i := 0; i < 20; i++ {
+			if code := record(router, http.MethodGet, "/server").Code; code != tt.want {
+				t.Fatalf("probability %v: status = %d, want %d", tt.probability, code, tt.want)
+			}
+		}
+		if fired := faults.Rules()[0].Fired; (tt.want == http.StatusCreated) != (fired == 0) {
+			t.Errorf("probability %v: fired %d times", tt.probability, fired)
+		}
+	}
+
+	// Правило с частичной вероятностью срабатывает не на каждом запросе
+	half := 0.5
+	router, faults := newFaultRouter(t, FaultRule{Action: FaultActionError, Probability: &half})
+	for i := 0; i < 200; i++ {
+		record(router, http.MethodGet, "/server")
+	}
+	if fired := faults.Rules()[0].Fired; fired == 0 || fired == 200 {
+		t.Errorf("probability 0.5 fired %d of 200 times", fired)
+	}
+}
+
+func TestFaultRuleMatching(t *testing.T) {
+	router, _ := newFaultRouter(t, FaultRule{Method: "post", Path: "/server/*", Action: FaultActionError, Status: http.StatusConflict})
+	if code := record(router, http.MethodPost, "/server/321").Code; code != http.StatusConflict {
+		t.Errorf("matching request: status = %d, want 409", code)
+	}
+	if code := record(router, http.MethodGet, "/server").Code; code != http.StatusCreated {
+		t.Errorf("other method and path: status = %d, want 201", code)
+	}
+}
+
+func TestFaultRuleValidate(t *testing.T) {
+	tooLikely := 1.5
+	for _, rule := range []FaultRule{
+		{Action: "explode"},
+		{Action: FaultActionLatency},
+		{Action: FaultActionLatency, Latency: "soon"},
+		{Action: FaultActionDrop, Path: "[/server"},
+		{Action: FaultActionDrop, Probability: &tooLikely},
+	} {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("%+v accepted", rule)
+		}
+	}
+}
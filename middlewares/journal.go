@@ -0,0 +1,162 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"hetzner-api-emulator/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxJournalBody сколько байт тела запроса сохраняется в журнале
+const maxJournalBody = 64 * 1024
+
+// JournalEntry запись журнала об одном аутентифицированном запросе
+type JournalEntry struct {
+	ID     int64               `json:"id"`
+	Time   time.Time           `json:"time"`
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Query  map[string][]string `json:"query,omitempty"`
+	Form   map[string][]string `json:"form,omitempty"`
+	Body   string              `json:"body,omitempty"` // Тело запроса, если это не форма
+	UserID int                 `json:"user_id"`
+	Status int                 `json:"status,omitempty"` // Статус, ушедший клиенту; нет, если сбой drop закрыл соединение без ответа
+	Fault  string              `json:"fault,omitempty"`  // Действие внедрённого сбоя: error, latency, drop или truncate
+}
+
+// JournalFilter условия выборки из журнала; пустые поля не ограничивают выборку
+type JournalFilter struct {
+	Method string
+	Path   string // Точный путь или шаблон path.Match, например /server/*/cancellation
+	UserID int
+	Status int
+	Fault  string
+	Since  time.Time
+	Until  time.Time
+}
+
+func (f JournalFilter) matches(entry JournalEntry) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, entry.Method) {
+		return false
+	}
+	if f.Path != "" {
+		if ok, _ := path.Match(f.Path, entry.Path); !ok {
+			return false
+		}
+	}
+	if f.UserID != 0 && f.UserID != entry.UserID {
+		return false
+	}
+	if f.Status != 0 && f.Status != entry.Status {
+		return false
+	}
+	if f.Fault != "" && f.Fault != entry.Fault {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Journal хранит последние запросы в памяти; самые старые записи вытесняются по достижении лимита
+type Journal struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	max     int
+	nextID  int64
+	entries []JournalEntry
+}
+
+// NewJournal создаёт журнал на max записей
+func NewJournal(clk clock.Clock, max int) *Journal {
+	if max <= 0 {
+		max = 10000
+	}
+	return &Journal{clock: clk, max: max, nextID: 1}
+}
+
+// Entries возвращает записи, подходящие под фильтр, в порядке поступления
+func (j *Journal) Entries(filter JournalFilter) []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	result := []JournalEntry{}
+	for _, entry := range j.entries {
+		if filter.matches(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// Clear очищает журнал
+func (j *Journal) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = nil
+}
+
+func (j *Journal) add(entry JournalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry.ID = j.nextID
+	j.nextID++
+	j.entries = append(j.entries, entry)
+	if len(j.entries) > j.max {
+		j.entries = j.entries[len(j.entries)-j.max:]
+	}
+}
+
+// Middleware записывает запрос и статус ответа; подключается сразу после DBAuthMiddleware,
+// чтобы в журнал попадали и ответы ограничителя запросов и внедрённые сбои
+func (j *Journal) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entry := JournalEntry{
+			Time:   j.clock.Now(),
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			entry.Query = query
+		}
+		if userID, err := GetUserIDFromContext(c); err == nil {
+			entry.UserID = userID
+		}
+
+		// Читаем тело и возвращаем его обратно, чтобы обработчики могли прочитать его сами
+		if c.Request.Body != nil {
+			body, _ := io.ReadAll(io.LimitReader(c.Request.Body, maxJournalBody))
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+			if strings.HasPrefix(c.ContentType(), "application/x-www-form-urlencoded") {
+				if form, err := url.ParseQuery(string(body)); err == nil && len(form) > 0 {
+					entry.Form = form
+				}
+			} else if len(body) > 0 {
+				entry.Body = string(body)
+			}
+		}
+
+		c.Next()
+
+		entry.Status = c.Writer.Status()
+		// При перехвате соединения статус писателя gin остаётся 200 и не отражает того, что получил клиент
+		if action := c.GetString(faultActionKey); action != "" {
+			entry.Fault = action
+			if status, ok := c.Get(faultStatusKey); ok {
+				entry.Status = status.(int)
+			}
+		}
+		j.add(entry)
+	}
+}
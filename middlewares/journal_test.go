@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newJournalRouter подключает журнал после подстановки user_id, как после DBAuthMiddleware.
+// Обработчик отвечает статусом из параметра status и возвращает прочитанное им значение server_name
+func newJournalRouter(t *testing.T, journal *Journal) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authenticate := func(c *gin.Context) { c.Set("user_id", 7) }
+	handler := func(c *gin.Context) {
+		status := http.StatusOK
+		if c.Query("status") == "404" {
+			status = http.StatusNotFound
+		}
+		c.String(status, c.PostForm("server_name"))
+	}
+	router.GET("/server", authenticate, journal.Middleware(), handler)
+	router.POST("/server/:server-number", authenticate, journal.Middleware(), handler)
+	return router
+}
+
+func sendJournal(router *gin.Engine, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestJournalRecordsRequest(t *testing.T) {
+	clk := clock.New()
+	at := clk.Freeze()
+	journal := NewJournal(clk, 0)
+	router := newJournalRouter(t, journal)
+
+	// Журнал читает тело, но обработчик всё равно получает форму целиком
+	recorder := sendJournal(router, http.MethodPost, "/server/321?verbose=1", "application/x-www-form-urlencoded", "server_name=web1")
+	if recorder.Body.String() != "web1" {
+		t.Fatalf("handler read %q, want the form to reach it", recorder.Body)
+	}
+	sendJournal(router, http.MethodPost, "/server/421", "application/json", `{"server_name":"web2"}`)
+
+	entries := journal.Entries(JournalFilter{})
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	want := JournalEntry{ID: 1, Time: at, Method: http.MethodPost, Path: "/server/321", Query: map[string][]string{"verbose": {"1"}},
+		Form: map[string][]string{"server_name": {"web1"}}, UserID: 7, Status: http.StatusOK}
+	if !reflect.DeepEqual(entries[0], want) {
+		t.Errorf("form entry = %+v, want %+v", entries[0], want)
+	}
+	if entries[1].ID != 2 || entries[1].Form != nil || entries[1].Body != `{"server_name":"web2"}` {
+		t.Errorf("JSON entry = %+v, want the raw body", entries[1])
+	}
+
+	journal.Clear()
+	if entries := journal.Entries(JournalFilter{}); len(entries) != 0 {
+		t.Errorf("after Clear: %d entries", len(entries))
+	}
+}
+
+func TestJournalKeepsLatestEntries(t *testing.T) {
+	journal := NewJournal(clock.New(), 2)
+	router := newJournalRouter(t, journal)
+	for i := 0; i < 3; i++ {
+		sendJournal(router, http.MethodGet, "/server", "", "")
+	}
+
+	entries := journal.Entries(JournalFilter{})
+	if len(entries) != 2 || entries[0].ID != 2 || entries[1].ID != 3 {
+		t.Errorf("entries = %+v, want the last two", entries)
+	}
+}
+
+func TestJournalFilter(t *testing.T) {
+	clk := clock.New()
+	start := clk.Freeze()
+	journal := NewJournal(clk, 0)
+	router := newJournalRouter(t, journal)
+
+	sendJournal(router, http.MethodGet, "/server", "", "")
+	clk.Advance(time.Hour)
+	sendJournal(router, http.MethodPost, "/server/321", "application/x-www-form-urlencoded", "server_name=web1")
+	clk.Advance(time.Hour)
+	sendJournal(router, http.MethodGet, "/server?status=404", "", "")
+
+	tests := []struct {
+		name    string
+		filter  JournalFilter
+		wantIDs []int64
+	}{
+		{"all", JournalFilter{}, []int64{1, 2, 3}},
+		{"method in any case", JournalFilter{Method: "get"}, []int64{1, 3}},
+		{"path pattern", JournalFilter{Path: "/server/*"}, []int64{2}},
+		{"exact path", JournalFilter{Path: "/server"}, []int64{1, 3}},
+		{"status", JournalFilter{Status: http.StatusNotFound}, []int64{3}},
+		{"user", JournalFilter{UserID: 7}, []int64{1, 2, 3}},
+		{"other user", JournalFilter{UserID: 8}, []int64{}},
+		{"since", JournalFilter{Since: start.Add(30 * time.Minute)}, []int64{2, 3}},
+		{"until", JournalFilter{Until: start.Add(time.Hour)}, []int64{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := []int64{}
+			for _, entry := range journal.Entries(tt.filter) {
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package middlewares
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hetzner-api-emulator/clock"
+)
+
+// LockoutSettings блокировка IP после неудачных входов: MaxFailures неверных паролей за Window секунд
+// блокируют адрес на BlockFor секунд. MaxFailures = 0 отключает блокировку
+type LockoutSettings struct {
+	MaxFailures int `json:"max_failures"`
+	Window      int `json:"window"`
+	BlockFor    int `json:"block_for"`
+}
+
+// DefaultLockoutSettings настройки блокировки, близкие к Robot
+func DefaultLockoutSettings() LockoutSettings {
+	return LockoutSettings{MaxFailures: 3, Window: 600, BlockFor: 600}
+}
+
+// ParseLockoutSettings разбирает настройки вида "3/600/600" (неудач/окно/блокировка в секундах).
+// "default" включает настройки Robot, пустая строка или "off" — отключает блокировку
+func ParseLockoutSettings(value string) (LockoutSettings, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "", "off":
+		return LockoutSettings{}, nil
+	case "default":
+		return DefaultLockoutSettings(), nil
+	}
+
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 {
+		return LockoutSettings{}, fmt.Errorf("invalid lockout %q, expected max_failures/window/block_for", value)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		number, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || number <= 0 {
+			return LockoutSettings{}, fmt.Errorf("invalid lockout %q, expected positive integers", value)
+		}
+		numbers[i] = number
+	}
+	return LockoutSettings{MaxFailures: numbers[0], Window: numbers[1], BlockFor: numbers[2]}, nil
+}
+
+// Validate проверяет настройки, заданные через административный API
+func (s LockoutSettings) Validate() error {
+	if s.MaxFailures < 0 || s.Window < 0 || s.BlockFor < 0 {
+		return fmt.Errorf("max_failures, window and block_for must not be negative")
+	}
+	if s.MaxFailures > 0 && (s.Window == 0 || s.BlockFor == 0) {
+		return fmt.Errorf("window and block_for are required when max_failures is set")
+	}
+	return nil
+}
+
+// LockoutBlock заблокированный адрес
+type LockoutBlock struct {
+	IP    string    `json:"ip"`
+	Until time.Time `json:"until"`
+}
+
+// lockoutSweepInterval как часто из счётчиков удаляются адреса, чьи окна и блокировки истекли
+const lockoutSweepInterval = time.Minute
+
+// LoginLockout считает неудачные входы по IP источника и временно блокирует адрес.
+// Время берётся из виртуальных часов, поэтому перевод часов вперёд снимает блокировку
+type LoginLockout struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	settings  LockoutSettings
+	failures  map[string][]time.Time
+	blocked   map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewLoginLockout создаёт счётчик неудачных входов с заданными настройками
+func NewLoginLockout(clk clock.Clock, settings LockoutSettings) *LoginLockout {
+	return &LoginLockout{
+		clock:    clk,
+		settings: settings,
+		failures: map[string][]time.Time{},
+		blocked:  map[string]time.Time{},
+	}
+}
+
+// Settings возвращает текущие настройки
+func (l *LoginLockout) Settings() LockoutSettings {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings
+}
+
+// SetSettings заменяет настройки, счётчики и блокировки сохраняются
+func (l *LoginLockout) SetSettings(settings LockoutSettings) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings = settings
+}
+
+// Blocks возвращает действующие блокировки
+func (l *LoginLockout) Blocks() []LockoutBlock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	result := []LockoutBlock{}
+	for ip, until := range l.blocked {
+		if now.Before(until) {
+			result = append(result, LockoutBlock{IP: ip, Until: until})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].IP < result[j].IP })
+	return result
+}
+
+// Unblock снимает блокировку и обнуляет счётчик адреса
+func (l *LoginLockout) Unblock(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.blocked, ip)
+	delete(l.failures, ip)
+}
+
+// Reset снимает все блокировки и обнуляет счётчики
+func (l *LoginLockout) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failures = map[string][]time.Time{}
+	l.blocked = map[string]time.Time{}
+}
+
+// Blocked сообщает, заблокирован ли адрес сейчас
+func (l *LoginLockout) Blocked(ip string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until, ok := l.blocked[ip]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !l.clock.Now().Before(until) {
+		delete(l.blocked, ip)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// RecordFailure учитывает неудачный вход и сообщает, заблокирован ли адрес в результате
+func (l *LoginLockout) RecordFailure(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	l.sweep(now)
+	if l.settings.MaxFailures == 0 {
+		return false
+	}
+
+	times := pruneWindow(l.failures[ip], now, RateLimit{Interval: l.settings.Window})
+	times = append(times, now)
+	if len(times) < l.settings.MaxFailures {
+		l.failures[ip] = times
+		return false
+	}
+	delete(l.failures, ip)
+	l.blocked[ip] = now.Add(time.Duration(l.settings.BlockFor) * time.Second)
+	return true
+}
+
+// RecordSuccess обнуляет счётчик неудач адреса после успешного входа
+func (l *LoginLockout) RecordSuccess(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, ip)
+}
+
+// sweep раз в lockoutSweepInterval удаляет адреса без неудач в текущем окне и с истёкшей блокировкой,
+// иначе каждый адрес, хоть раз ошибившийся паролем, оставался бы в памяти навсегда
+func (l *LoginLockout) sweep(now time.Time) {
+	// Часы можно перевести назад, тогда отсчёт начинается заново
+	if now.Sub(l.lastSweep) < lockoutSweepInterval && !now.Before(l.lastSweep) {
+		return
+	}
+	l.lastSweep = now
+
+	window := RateLimit{Interval: l.settings.Window}
+	for ip, times := range l.failures {
+		if times = pruneWindow(times, now, window); len(times) == 0 {
+			delete(l.failures, ip)
+		} else {
+			l.failures[ip] = times
+		}
+	}
+	for ip, until := range l.blocked {
+		if !now.Before(until) {
+			delete(l.blocked, ip)
+		}
+	}
+}
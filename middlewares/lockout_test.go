@@ -0,0 +1,160 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/clock"
+)
+
+func TestParseLockoutSettings(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    LockoutSettings
+		wantErr bool
+	}{
+		{value: "", want: LockoutSettings{}},
+		{value: "off", want: LockoutSettings{}},
+		{value: "default", want: DefaultLockoutSettings()},
+		{value: "5/60/300", want: LockoutSettings{MaxFailures: 5, Window: 60, BlockFor: 300}},
+		{value: " 5 / 60 / 300 ", want: LockoutSettings{MaxFailures: 5, Window: 60, BlockFor: 300}},
+		{value: "5/60", wantErr: true},
+		{value: "5/0/300", wantErr: true},
+		{value: "five/60/300", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLockoutSettings(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockoutSettingsValidate(t *testing.T) {
+	tests := []struct {
+		settings LockoutSettings
+		wantErr  bool
+	}{
+		{LockoutSettings{}, false},
+		{LockoutSettings{MaxFailures: 3, Window: 600, BlockFor: 600}, false},
+		{LockoutSettings{MaxFailures: 3}, true},
+		{LockoutSettings{MaxFailures: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.settings.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v: err = %v, wantErr %v", tt.settings, err, tt.wantErr)
+		}
+	}
+}
+
+func newTestLockout(settings LockoutSettings) (*LoginLockout, *clock.Virtual) {
+	clk := clock.New()
+	clk.Freeze()
+	return NewLoginLockout(clk, settings), clk
+}
+
+func TestLoginLockoutBlocksAfterFailures(t *testing.T) {
+	lockout, clk := newTestLockout(LockoutSettings{MaxFailures: 3, Window: 60, BlockFor: 300})
+	const ip = "203.0.113.7"
+
+	steps := []struct {
+		name        string
+		advance     time.Duration
+		failure     bool
+		wantBlocked bool
+	}{
+		{"first failure", 0, true, false},
+		{"second failure", 10 * time.Second, true, false},
+		{"first failure left the window", 55 * time.Second, true, false},
+		{"third failure in the window", 0, true, true},
+		{"still blocked", 299 * time.Second, false, true},
+		{"block expired", time.Second, false, false},
+	}
+	for _, step := range steps {
+		clk.Advance(step.advance)
+		if step.failure {
+			if blocked := lockout.RecordFailure(ip); blocked != step.wantBlocked {
+				t.Errorf("%s: RecordFailure = %v, want %v", step.name, blocked, step.wantBlocked)
+			}
+		}
+		if _, blocked := lockout.Blocked(ip); blocked != step.wantBlocked {
+			t.Errorf("%s: Blocked = %v, want %v", step.name, blocked, step.wantBlocked)
+		}
+	}
+	if _, blocked := lockout.Blocked("198.51.100.1"); blocked {
+		t.Error("another address is blocked")
+	}
+}
+
+func TestLoginLockoutDisabled(t *testing.T) {
+	lockout, _ := newTestLockout(LockoutSettings{})
+	for i := 0; i < 10; i++ {
+		if lockout.RecordFailure("203.0.113.7") {
+			t.Fatal("disabled lockout blocked an address")
+		}
+	}
+}
+
+func TestLoginLockoutSuccessAndUnblock(t *testing.T) {
+	lockout, _ := newTestLockout(LockoutSettings{MaxFailures: 2, Window: 60, BlockFor: 60})
+
+	lockout.RecordFailure("203.0.113.7")
+	lockout.RecordSuccess("203.0.113.7")
+	if lockout.RecordFailure("203.0.113.7") {
+		t.Error("failures before a successful login were still counted")
+	}
+
+	lockout.RecordFailure("198.51.100.1")
+	lockout.RecordFailure("198.51.100.1")
+	lockout.RecordFailure("198.51.100.2")
+	lockout.RecordFailure("198.51.100.2")
+	if blocks := lockout.Blocks(); len(blocks) != 2 || blocks[0].IP != "198.51.100.1" {
+		t.Fatalf("Blocks = %+v, want both addresses", blocks)
+	}
+
+	lockout.Unblock("198.51.100.1")
+	if _, blocked := lockout.Blocked("198.51.100.1"); blocked {
+		t.Error("address still blocked after Unblock")
+	}
+	lockout.Reset()
+	if blocks := lockout.Blocks(); len(blocks) != 0 {
+		t.Errorf("Blocks after Reset = %+v", blocks)
+	}
+}
+
+func TestLoginLockoutEvictsExpiredEntries(t *testing.T) {
+	lockout, clk := newTestLockout(LockoutSettings{MaxFailures: 2, Window: 60, BlockFor: 120})
+
+	// Адреса, которые ошиблись и больше не вернулись
+	lockout.RecordFailure("198.51.100.1")
+	lockout.RecordFailure("198.51.100.2")
+	lockout.RecordFailure("198.51.100.2")
+	if len(lockout.failures) != 1 || len(lockout.blocked) != 1 {
+		t.Fatalf("tracked %d failures and %d blocks, want 1 and 1", len(lockout.failures), len(lockout.blocked))
+	}
+
+	// Окно прошло, блокировка ещё действует
+	clk.Advance(90 * time.Second)
+	lockout.RecordFailure("203.0.113.7")
+	if _, ok := lockout.failures["198.51.100.1"]; ok {
+		t.Error("failures outside the window were kept")
+	}
+	if _, ok := lockout.blocked["198.51.100.2"]; !ok {
+		t.Error("an active block was evicted")
+	}
+
+	// Блокировка истекла
+	clk.Advance(time.Minute)
+	lockout.RecordFailure("203.0.113.8")
+	if _, ok := lockout.blocked["198.51.100.2"]; ok {
+		t.Error("an expired block was kept")
+	}
+	if len(lockout.failures) != 1 {
+		t.Errorf("tracked failures = %v, want only the latest address", lockout.failures)
+	}
+}
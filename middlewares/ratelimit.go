@@ -0,0 +1,206 @@
+package middlewares
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hetzner-api-emulator/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit квота на группу маршрутов: не больше MaxRequest запросов за Interval секунд
+type RateLimit struct {
+	Group      string `json:"group"` // Первый сегмент пути: server, reset, boot...
+	MaxRequest int    `json:"max_request"`
+	Interval   int    `json:"interval"`
+}
+
+// DefaultRateLimits квоты, как в Robot
+func DefaultRateLimits() []RateLimit {
+	return []RateLimit{
+		{Group: "server", MaxRequest: 200, Interval: 3600},
+		{Group: "reset", MaxRequest: 50, Interval: 3600},
+		{Group: "boot", MaxRequest: 500, Interval: 3600},
+		{Group: "wol", MaxRequest: 50, Interval: 3600},
+		{Group: "ip", MaxRequest: 5000, Interval: 3600},
+		{Group: "subnet", MaxRequest: 5000, Interval: 3600},
+		{Group: "firewall", MaxRequest: 500, Interval: 3600},
+		{Group: "storagebox", MaxRequest: 200, Interval: 3600},
+	}
+}
+
+// ParseRateLimits разбирает квоты вида "server=200/3600,reset=50/3600".
+// "default" включает квоты Robot, пустая строка или "off" — отключает ограничения
+func ParseRateLimits(value string) ([]RateLimit, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "", "off":
+		return nil, nil
+	case "default":
+		return DefaultRateLimits(), nil
+	}
+
+	var limits []RateLimit
+	for _, item := range strings.Split(value, ",") {
+		group, quota, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q, expected group=max/interval", item)
+		}
+		maxStr, intervalStr, ok := strings.Cut(quota, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q, expected group=max/interval", item)
+		}
+		maxRequest, err := strconv.Atoi(maxStr)
+		if err != nil || maxRequest <= 0 {
+			return nil, fmt.Errorf("invalid max_request in %q", item)
+		}
+		interval, err := strconv.Atoi(intervalStr)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid interval in %q", item)
+		}
+		limits = append(limits, RateLimit{Group: group, MaxRequest: maxRequest, Interval: interval})
+	}
+	return limits, nil
+}
+
+// RateLimitUsage сколько запросов пользователь сделал в текущем окне
+type RateLimitUsage struct {
+	UserID int    `json:"user_id"`
+	Group  string `json:"group"`
+	Used   int    `json:"used"`
+}
+
+type rateLimitKey struct {
+	userID int
+	group  string
+}
+
+// RateLimiter считает запросы каждого пользователя по группам маршрутов в скользящем окне.
+// Время берётся из виртуальных часов, поэтому перевод часов вперёд освобождает квоту
+type RateLimiter struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	limits   map[string]RateLimit
+	requests map[rateLimitKey][]time.Time
+}
+
+// NewRateLimiter создаёт ограничитель с заданными квотами
+func NewRateLimiter(clk clock.Clock, limits []RateLimit) *RateLimiter {
+	limiter := &RateLimiter{clock: clk, requests: map[rateLimitKey][]time.Time{}}
+	limiter.SetLimits(limits)
+	return limiter
+}
+
+// SetLimits заменяет квоты, счётчики сохраняются
+func (l *RateLimiter) SetLimits(limits []RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = map[string]RateLimit{}
+	for _, limit := range limits {
+		l.limits[limit.Group] = limit
+	}
+}
+
+// Limits возвращает текущие квоты
+func (l *RateLimiter) Limits() []RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make([]RateLimit, 0, len(l.limits))
+	for _, limit := range l.limits {
+		result = append(result, limit)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Group < result[j].Group })
+	return result
+}
+
+// Usage возвращает использование квот в текущих окнах
+func (l *RateLimiter) Usage() []RateLimitUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	result := []RateLimitUsage{}
+	for key, times := range l.requests {
+		limit, ok := l.limits[key.group]
+		if !ok {
+			continue
+		}
+		used := len(pruneWindow(times, now, limit))
+		if used > 0 {
+			result = append(result, RateLimitUsage{UserID: key.userID, Group: key.group, Used: used})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UserID != result[j].UserID {
+			return result[i].UserID < result[j].UserID
+		}
+		return result[i].Group < result[j].Group
+	})
+	return result
+}
+
+// Reset обнуляет все счётчики
+func (l *RateLimiter) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = map[rateLimitKey][]time.Time{}
+}
+
+// allow учитывает запрос и сообщает, укладывается ли он в квоту
+func (l *RateLimiter) allow(userID int, group string) (RateLimit, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.limits[group]
+	if !ok {
+		return RateLimit{}, true
+	}
+
+	key := rateLimitKey{userID: userID, group: group}
+	now := l.clock.Now()
+	times := pruneWindow(l.requests[key], now, limit)
+	if len(times) >= limit.MaxRequest {
+		l.requests[key] = times
+		return limit, false
+	}
+	l.requests[key] = append(times, now)
+	return limit, true
+}
+
+// pruneWindow отбрасывает запросы, выпавшие из окна
+func pruneWindow(times []time.Time, now time.Time, limit RateLimit) []time.Time {
+	windowStart := now.Add(-time.Duration(limit.Interval) * time.Second)
+	i := 0
+	for i < len(times) && !times[i].After(windowStart) {
+		i++
+	}
+	return times[i:]
+}
+
+// routeGroup возвращает первый сегмент пути: /server/321/cancellation -> server
+func routeGroup(path string) string {
+	group, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return group
+}
+
+// Middleware ограничивает запросы; подключается после DBAuthMiddleware
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserIDFromContext(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		limit, ok := l.allow(userID, routeGroup(c.Request.URL.Path))
+		if !ok {
+			RespondWithRobotError(c, NewRateLimitExceeded(limit.MaxRequest, limit.Interval))
+			return
+		}
+		c.Next()
+	}
+}
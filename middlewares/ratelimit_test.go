@@ -0,0 +1,151 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"hetzner-api-emulator/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []RateLimit
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "off", want: nil},
+		{value: "default", want: DefaultRateLimits()},
+		{value: "server=200/3600", want: []RateLimit{{Group: "server", MaxRequest: 200, Interval: 3600}}},
+		{value: " server=2/60, reset=1/10 ", want: []RateLimit{{Group: "server", MaxRequest: 2, Interval: 60}, {Group: "reset", MaxRequest: 1, Interval: 10}}},
+		{value: "server", wantErr: true},
+		{value: "server=200", wantErr: true},
+		{value: "server=0/3600", wantErr: true},
+		{value: "server=200/-1", wantErr: true},
+		{value: "server=many/3600", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRateLimits(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteGroup(t *testing.T) {
+	tests := map[string]string{
+		"/server":                  "server",
+		"/server/321":              "server",
+		"/server/321/cancellation": "server",
+		"/reset/321":               "reset",
+		"/":                        "",
+	}
+	for path, want := range tests {
+		if got := routeGroup(path); got != want {
+			t.Errorf("routeGroup(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRateLimiterWindow(t *testing.T) {
+	clk := clock.New()
+	clk.Freeze()
+	limiter := NewRateLimiter(clk, []RateLimit{{Group: "server", MaxRequest: 2, Interval: 60}})
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		userID  int
+		group   string
+		want    bool
+	}{
+		{"first request", 0, 1, "server", true},
+		{"second request", 10 * time.Second, 1, "server", true},
+		{"over quota", 10 * time.Second, 1, "server", false},
+		{"other user has own quota", 0, 2, "server", true},
+		{"group without limit", 0, 1, "reset", true},
+		{"first request left the window", 41 * time.Second, 1, "server", true},
+		{"second request still in the window", 0, 1, "server", false},
+	}
+	for _, step := range steps {
+		clk.Advance(step.advance)
+		if _, got := limiter.allow(step.userID, step.group); got != step.want {
+			t.Errorf("%s: allow = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestRateLimiterUsageAndReset(t *testing.T) {
+	clk := clock.New()
+	clk.Freeze()
+	limiter := NewRateLimiter(clk, []RateLimit{{Group: "server", MaxRequest: 5, Interval: 60}, {Group: "reset", MaxRequest: 5, Interval: 60}})
+	limiter.allow(2, "reset")
+	limiter.allow(1, "server")
+	limiter.allow(1, "server")
+
+	want := []RateLimitUsage{{UserID: 1, Group: "server", Used: 2}, {UserID: 2, Group: "reset", Used: 1}}
+	if got := limiter.Usage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Usage = %+v, want %+v", got, want)
+	}
+
+	clk.Advance(time.Minute)
+	if got := limiter.Usage(); len(got) != 0 {
+		t.Errorf("Usage after the window = %+v, want empty", got)
+	}
+
+	limiter.allow(1, "server")
+	limiter.Reset()
+	if got := limiter.Usage(); len(got) != 0 {
+		t.Errorf("Usage after Reset = %+v, want empty", got)
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clk := clock.New()
+	clk.Freeze()
+	limiter := NewRateLimiter(clk, []RateLimit{{Group: "server", MaxRequest: 1, Interval: 3600}})
+
+	router := gin.New()
+	router.GET("/server", func(c *gin.Context) { c.Set("user_id", 1) }, limiter.Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	codes := make([]int, 2)
+	var body struct {
+		Error struct {
+			Status     int    `json:"status"`
+			Code       string `json:"code"`
+			MaxRequest int    `json:"max_request"`
+			Interval   int    `json:"interval"`
+		} `json:"error"`
+	}
+	for i := range codes {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/server", nil))
+		codes[i] = recorder.Code
+		if i == 1 {
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusForbidden {
+		t.Fatalf("status codes = %v, want [200 403]", codes)
+	}
+	if body.Error.Code != "RATE_LIMIT_EXCEEDED" || body.Error.MaxRequest != 1 || body.Error.Interval != 3600 {
+		t.Errorf("error = %+v", body.Error)
+	}
+}
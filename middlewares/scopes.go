@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"hetzner-api-emulator/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserScopesFromContext возвращает области доступа пользователя, сохранённые DBAuthMiddleware
+func GetUserScopesFromContext(c *gin.Context) []string {
+	scopes, _ := c.Get("user_scopes")
+	list, _ := scopes.([]string)
+	return list
+}
+
+// ScopeMiddleware проверяет, что у пользователя есть область доступа к группе маршрутов запроса
+// (server:read для GET /server/321, reset:write для POST /reset/321). Подключается после DBAuthMiddleware
+func ScopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required := models.RequiredScope(c.Request.Method, routeGroup(c.Request.URL.Path))
+		if !models.HasScope(GetUserScopesFromContext(c), required) {
+			RespondWithRobotError(c, NewRobotError(http.StatusForbidden, "FORBIDDEN", "Missing permission "+required))
+			return
+		}
+		c.Next()
+	}
+}
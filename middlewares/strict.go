@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"hetzner-api-emulator/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StrictValidation проверяет запросы по описанию OpenAPI и отвечает INVALID_INPUT со списками missing и invalid,
+// как Robot. Маршруты, которых нет в описании, не проверяются
+func StrictValidation(validator *openapi.Validator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pathParams := map[string]string{}
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+
+		// Ошибку разбора тела вернёт сам обработчик, здесь проверяем только то, что удалось разобрать
+		_ = c.Request.ParseForm()
+
+		missing, invalid, found := validator.Validate(openapi.Request{
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			PathParams: pathParams,
+			Query:      c.Request.URL.Query(),
+			Form:       c.Request.PostForm,
+		})
+		if !found || (len(missing) == 0 && len(invalid) == 0) {
+			c.Next()
+			return
+		}
+
+		RespondWithRobotError(c, NewInvalidInput(missing, invalid))
+	}
+}
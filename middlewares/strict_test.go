@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hetzner-api-emulator/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newStrictRouter(t *testing.T, strict bool) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if strict {
+		validator, err := openapi.NewValidator()
+		if err != nil {
+			t.Fatalf("NewValidator: %v", err)
+		}
+		router.Use(StrictValidation(validator))
+	}
+	handled := func(c *gin.Context) { c.String(http.StatusOK, "handled") }
+	router.GET("/server", handled)
+	router.POST("/server/:server-number", handled)
+	router.GET("/reset", handled)
+	return router
+}
+
+func TestStrictValidation(t *testing.T) {
+	tests := []struct {
+		name, method, target, form string
+		wantMissing, wantInvalid   []string
+	}{
+		{name: "valid request", method: http.MethodPost, target: "/server/321", form: "server_name=web1"},
+		{name: "route outside the description", method: http.MethodGet, target: "/reset?anything=1"},
+		{name: "missing parameter", method: http.MethodPost, target: "/server/321", wantMissing: []string{"server_name"}},
+		{name: "unknown parameter", method: http.MethodPost, target: "/server/321", form: "server_name=web1&colour=red", wantInvalid: []string{"colour"}},
+		{name: "bad path type", method: http.MethodPost, target: "/server/abc", form: "server_name=web1", wantInvalid: []string{"server-number"}},
+		{name: "unknown query parameter", method: http.MethodGet, target: "/server?page=2", wantInvalid: []string{"page"}},
+	}
+	for _, strict := range []bool{true, false} {
+		router := newStrictRouter(t, strict)
+		for _, tt := range tests {
+			name := tt.name
+			if !strict {
+				name = "off/" + name
+			}
+			t.Run(name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.form))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+
+				// Без строгого режима любой запрос доходит до обработчика как раньше
+				if !strict || (tt.wantMissing == nil && tt.wantInvalid == nil) {
+					if recorder.Code != http.StatusOK || recorder.Body.String() != "handled" {
+						t.Errorf("status = %d, body %s, want the handler to run", recorder.Code, recorder.Body)
+					}
+					return
+				}
+
+				var body struct {
+					Error RobotError `json:"error"`
+				}
+				if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+					t.Fatalf("decode %s: %v", recorder.Body, err)
+				}
+				wantMissing, wantInvalid := tt.wantMissing, tt.wantInvalid
+				if wantMissing == nil {
+					wantMissing = []string{}
+				}
+				if wantInvalid == nil {
+					wantInvalid = []string{}
+				}
+				if recorder.Code != http.StatusBadRequest || body.Error.Code != "INVALID_INPUT" ||
+					!reflect.DeepEqual(body.Error.Missing, wantMissing) || !reflect.DeepEqual(body.Error.Invalid, wantInvalid) {
+					t.Errorf("status = %d, error %+v, want 400 INVALID_INPUT missing %v invalid %v", recorder.Code, body.Error, wantMissing, wantInvalid)
+				}
+			})
+		}
+	}
+}
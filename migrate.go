@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"hetzner-api-emulator/migrations"
+)
+
+// runMigrate выполняет команду migrate up|down|status против базы из конфигурации
+func runMigrate(args []string) error {
+	usage := "usage: migrate up [-to version] | migrate down [-steps n] | migrate status"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	action := args[0]
+	if action != "up" && action != "down" && action != "status" {
+		return fmt.Errorf("unknown migrate command %q, %s", action, usage)
+	}
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	toFlag := fs.Int("to", 0, "Apply migrations up to this version (default: latest)")
+	stepsFlag := fs.Int("steps", 1, "Number of migrations to roll back")
+	_, db, positional, err := openDatabase(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errors.New(usage)
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrations.Up(db, *toFlag)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Schema is up to date")
+			return nil
+		}
+		log.Printf("Applied %d migrations", len(applied))
+	case "down":
+		rolledBack, err := migrations.Down(db, *stepsFlag)
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back %d migrations", len(rolledBack))
+	case "status":
+		statuses, err := migrations.StatusOf(db)
+		if err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		printMigrationStatus(statuses)
+	}
+	return nil
+}
+
+// printMigrationStatus печатает таблицу версий схемы
+func printMigrationStatus(statuses []migrations.Status) {
+	w := newTableWriter()
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		if status.Unknown {
+			appliedAt += " (unknown to this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	w.Flush()
+}
@@ -0,0 +1,209 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration одна версия схемы. Up и Down выполняются в транзакции вместе с записью в schema_migrations
+// (MySQL фиксирует DDL сразу, поэтому там откат транзакции не отменяет уже выполненные изменения схемы)
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration строка таблицы версий схемы: одна на каждую применённую миграцию
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName имя таблицы версий
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status состояние одной миграции
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Unknown   bool       `json:"unknown,omitempty"` // Версия есть в базе, но не известна этой сборке
+}
+
+// All возвращает все известные миграции по возрастанию версии
+func All() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+// Latest номер последней известной версии схемы
+func Latest() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// applied читает таблицу версий. create создаёт её при первом запуске, иначе отсутствие таблицы означает пустую схему
+func applied(db *gorm.DB, create bool) (map[int]SchemaMigration, error) {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		if !create {
+			return map[int]SchemaMigration{}, nil
+		}
+		if err := db.Migrator().CreateTable(&SchemaMigration{}); err != nil {
+			return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+	}
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	result := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// Current возвращает последнюю применённую версию схемы (0 — ни одной)
+func Current(db *gorm.DB) (int, error) {
+	done, err := applied(db, false)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range done {
+		if version > current {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// Up применяет неприменённые миграции до версии target включительно; target 0 — до последней
+func Up(db *gorm.DB, target int) ([]Migration, error) {
+	if target == 0 {
+		target = Latest()
+	}
+	if target < 0 || target > Latest() {
+		return nil, fmt.Errorf("unknown target version %d, latest is %d", target, Latest())
+	}
+
+	done, err := applied(db, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+	for _, migration := range migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return result, fmt.Errorf("migration %d %s failed: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("Applied migration %d %s", migration.Version, migration.Name)
+		result = append(result, migration)
+	}
+	return result, nil
+}
+
+// Down откатывает steps последних применённых миграций
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	done, err := applied(db, true)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(done))
+	for version := range done {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	known := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	var result []Migration
+	for _, version := range versions {
+		if len(result) == steps {
+			break
+		}
+		migration, ok := known[version]
+		if !ok {
+			return result, fmt.Errorf("version %d is applied but not known to this build, cannot roll it back", version)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, version).Error
+		})
+		if err != nil {
+			return result, fmt.Errorf("rollback of migration %d %s failed: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("Rolled back migration %d %s", migration.Version, migration.Name)
+		result = append(result, migration)
+	}
+	return result, nil
+}
+
+// StatusOf возвращает состояние всех известных миграций и версий, которые есть только в базе
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(done, migration.Version)
+		}
+		result = append(result, status)
+	}
+	for _, row := range done {
+		appliedAt := row.AppliedAt
+		result = append(result, Status{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Pending возвращает число известных, но не применённых миграций
+func Pending(db *gorm.DB) (int, error) {
+	statuses, err := StatusOf(db)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,177 @@
+package migrations_test
+
+import (
+	"strings"
+	"testing"
+
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	return db
+}
+
+func mustUp(t *testing.T, db *gorm.DB, target int) []migrations.Migration {
+	t.Helper()
+	applied, err := migrations.Up(db, target)
+	if err != nil {
+		t.Fatalf("Up(%d): %v", target, err)
+	}
+	return applied
+}
+
+func assertVersion(t *testing.T, db *gorm.DB, want int) {
+	t.Helper()
+	current, err := migrations.Current(db)
+	if err != nil {
+		t.Fatalf("Current: %v", err)
+	}
+	if current != want {
+		t.Errorf("current version = %d, want %d", current, want)
+	}
+}
+
+func TestUpAppliesAllMigrationsOnce(t *testing.T) {
+	db := newTestDB(t)
+	assertVersion(t, db, 0)
+
+	if applied := mustUp(t, db, 0); len(applied) != len(migrations.All()) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(migrations.All()))
+	}
+	assertVersion(t, db, migrations.Latest())
+	for _, table := range []string{"users", "servers", "ips", "auth_events", "schema_migrations"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s is missing", table)
+		}
+	}
+
+	if applied := mustUp(t, db, 0); len(applied) != 0 {
+		t.Errorf("second Up applied %d migrations, want 0", len(applied))
+	}
+	if pending, err := migrations.Pending(db); err != nil || pending != 0 {
+		t.Errorf("Pending = %d, %v, want 0", pending, err)
+	}
+}
+
+func TestUpToTarget(t *testing.T) {
+	db := newTestDB(t)
+	mustUp(t, db, 1)
+	assertVersion(t, db, 1)
+	if pending, err := migrations.Pending(db); err != nil || pending != migrations.Latest()-1 {
+		t.Errorf("Pending = %d, %v, want %d", pending, err, migrations.Latest()-1)
+	}
+
+	for _, target := range []int{-1, migrations.Latest() + 1} {
+		if _, err := migrations.Up(db, target); err == nil {
+			t.Errorf("Up(%d) succeeded, want an error", target)
+		}
+	}
+}
+
+func TestUpOverLegacyAutoMigrateSchema(t *testing.T) {
+	// База, созданная до версионирования через AutoMigrate моделей
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&models.User{}, &models.Server{}, &models.IP{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	if err := db.Create(&models.User{Username: "test", Password: "hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mustUp(t, db, 0)
+	var count int64
+	if err := db.Model(&models.User{}).Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("users after Up = %d, %v, want the existing user kept", count, err)
+	}
+}
+
+func TestDown(t *testing.T) {
+	db := newTestDB(t)
+	mustUp(t, db, 0)
+
+	if _, err := migrations.Down(db, 0); err == nil {
+		t.Error("Down(0) succeeded, want an error")
+	}
+
+	rolledBack, err := migrations.Down(db, 1)
+	if err != nil {
+		t.Fatalf("Down(1): %v", err)
+	}
+	if len(rolledBack) != 1 || rolledBack[0].Version != migrations.Latest() {
+		t.Errorf("rolled back %+v, want the latest migration", rolledBack)
+	}
+	assertVersion(t, db, migrations.Latest()-1)
+
+	// Больше шагов, чем применено: откатываются все
+	if _, err := migrations.Down(db, 100); err != nil {
+		t.Fatalf("Down(100): %v", err)
+	}
+	assertVersion(t, db, 0)
+	if db.Migrator().HasTable("users") {
+		t.Error("users table survived a full rollback")
+	}
+}
+
+func TestWidenIPAddressKeepsIndex(t *testing.T) {
+	db := newTestDB(t)
+	mustUp(t, db, 2)
+	if !db.Migrator().HasIndex(&models.IP{}, "ServerID") {
+		t.Error("ips.server_id index is missing after up")
+	}
+	if _, err := migrations.Down(db, 1); err != nil {
+		t.Fatalf("Down(1): %v", err)
+	}
+	if !db.Migrator().HasIndex(&models.IP{}, "ServerID") {
+		t.Error("ips.server_id index is missing after down")
+	}
+}
+
+func TestWidenIPAddressDownRefusesLongAddresses(t *testing.T) {
+	db := newTestDB(t)
+	mustUp(t, db, 2)
+	if err := db.Create(&models.IP{ServerID: 1, IPAddress: "2a01:4f8:111:4221::2", Mask: "128"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := migrations.Down(db, 1)
+	if err == nil || !strings.Contains(err.Error(), "longer than 15 characters") {
+		t.Fatalf("Down(1) = %v, want a refusal", err)
+	}
+	assertVersion(t, db, 2)
+}
+
+func TestStatusOfUnknownVersion(t *testing.T) {
+	db := newTestDB(t)
+	mustUp(t, db, 0)
+	unknown := migrations.Latest() + 10
+	if err := db.Create(&migrations.SchemaMigration{Version: unknown, Name: "from_a_newer_build"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := migrations.StatusOf(db)
+	if err != nil {
+		t.Fatalf("StatusOf: %v", err)
+	}
+	last := statuses[len(statuses)-1]
+	if last.Version != unknown || !last.Unknown || !last.Applied {
+		t.Errorf("last status = %+v, want the unknown version", last)
+	}
+	for _, status := range statuses[:len(statuses)-1] {
+		if !status.Applied || status.Unknown || status.AppliedAt == nil {
+			t.Errorf("status = %+v, want applied", status)
+		}
+	}
+
+	if _, err := migrations.Down(db, 1); err == nil {
+		t.Error("Down rolled back a version unknown to this build")
+	}
+}
@@ -0,0 +1,134 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations список версий схемы по возрастанию. Уже выпущенные миграции не меняются: новая схема — новая версия.
+// Каждая миграция описывает таблицы своими структурами-снимками, а не моделями из models,
+// чтобы последующие изменения моделей не меняли смысл старых версий
+var migrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+	{Version: 2, Name: "widen_ip_address_for_ipv6", Up: widenIPAddressUp, Down: widenIPAddressDown},
+}
+
+// Версия 1: схема, которую до версионирования создавал AutoMigrate.
+// Для базы, уже созданной через -migration, миграция только дополняет недостающее
+
+type userV1 struct {
+	ID         int       `gorm:"primaryKey;autoIncrement"`
+	Username   string    `gorm:"uniqueIndex;type:varchar(255)"`
+	Password   string    `gorm:"type:varchar(255)"`
+	Disabled   bool      `gorm:"default:false"`
+	AllowedIPs string    `gorm:"type:varchar(1024)"`
+	Scopes     string    `gorm:"type:varchar(1024)"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (userV1) TableName() string { return "users" }
+
+type serverV1 struct {
+	ID                  int    `gorm:"primaryKey;autoIncrement"`
+	UserID              int    `gorm:"not null"`
+	ServerNumber        int    `gorm:"not null;unique"`
+	ServerName          string `gorm:"type:varchar(255);not null"`
+	ServerIP            string `gorm:"type:varchar(255);"`
+	Product             string `gorm:"type:varchar(255);"`
+	ServerIPv6Net       string `gorm:"type:varchar(255);"`
+	DC                  string `gorm:"type:varchar(255);"`
+	Traffic             string `gorm:"type:varchar(255);"`
+	Status              string `gorm:"type:varchar(255);"`
+	Cancelled           bool   `gorm:"default:false"`
+	PaidUntil           *time.Time
+	Reset               bool           `gorm:"column:reset"`
+	Rescue              bool           `gorm:"column:rescue"`
+	Vnc                 bool           `gorm:"column:vnc"`
+	Windows             bool           `gorm:"column:windows"`
+	Plesk               bool           `gorm:"column:plesk"`
+	Cpanel              bool           `gorm:"column:cpanel"`
+	Wol                 bool           `gorm:"column:wol"`
+	HotSwap             bool           `gorm:"column:hot_swap"`
+	LinkedStoragebox    int            `gorm:"column:linked_storagebox"`
+	ReservationPossible bool           `gorm:"default:false"`
+	Reserved            bool           `gorm:"default:false"`
+	CancellationDate    *time.Time     `gorm:"column:cancellation_date"`
+	CancellationReason  string         `gorm:"type:varchar(255);"`
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
+}
+
+func (serverV1) TableName() string { return "servers" }
+
+type ipV1 struct {
+	ID        int    `gorm:"primaryKey;autoIncrement"`
+	ServerID  int    `gorm:"not null;index"`
+	IPAddress string `gorm:"type:varchar(15);not null"`
+	Mask      string `gorm:"type:varchar(15);not null"`
+}
+
+func (ipV1) TableName() string { return "ips" }
+
+type authEventV1 struct {
+	ID       int64     `gorm:"primaryKey;autoIncrement"`
+	Time     time.Time `gorm:"not null;index"`
+	Username string    `gorm:"type:varchar(255);index"`
+	UserID   int
+	SourceIP string `gorm:"type:varchar(45)"`
+	Method   string `gorm:"type:varchar(10)"`
+	Path     string `gorm:"type:varchar(255)"`
+	Result   string `gorm:"type:varchar(16);index"`
+	Reason   string `gorm:"type:varchar(64)"`
+}
+
+func (authEventV1) TableName() string { return "auth_events" }
+
+func baselineUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&userV1{}, &serverV1{}, &ipV1{}, &authEventV1{})
+}
+
+func baselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&authEventV1{}, &ipV1{}, &serverV1{}, &userV1{})
+}
+
+// Версия 2: IPv6-адрес длиннее 15 символов, varchar(45) вмещает и IPv4-mapped форму
+
+type ipV2 struct {
+	ID        int    `gorm:"primaryKey;autoIncrement"`
+	ServerID  int    `gorm:"not null;index"`
+	IPAddress string `gorm:"type:varchar(45);not null"`
+	Mask      string `gorm:"type:varchar(15);not null"`
+}
+
+func (ipV2) TableName() string { return "ips" }
+
+func widenIPAddressUp(tx *gorm.DB) error {
+	if err := tx.Migrator().AlterColumn(&ipV2{}, "IPAddress"); err != nil {
+		return err
+	}
+	return restoreIndex(tx, &ipV2{}, "ServerID")
+}
+
+func widenIPAddressDown(tx *gorm.DB) error {
+	// Откат не должен молча обрезать адреса
+	var long int64
+	if err := tx.Model(&ipV2{}).Where("LENGTH(ip_address) > ?", 15).Count(&long).Error; err != nil {
+		return err
+	}
+	if long > 0 {
+		return fmt.Errorf("%d IP addresses are longer than 15 characters, delete them before rolling back", long)
+	}
+	if err := tx.Migrator().AlterColumn(&ipV1{}, "IPAddress"); err != nil {
+		return err
+	}
+	return restoreIndex(tx, &ipV1{}, "ServerID")
+}
+
+// restoreIndex создаёт индекс заново, если он пропал: SQLite меняет тип столбца пересозданием таблицы без индексов
+func restoreIndex(tx *gorm.DB, model interface{}, field string) error {
+	if tx.Migrator().HasIndex(model, field) {
+		return nil
+	}
+	return tx.Migrator().CreateIndex(model, field)
+}
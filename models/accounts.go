@@ -0,0 +1,57 @@
+package models
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"gorm.io/gorm"
+)
+
+// WebServiceUsernamePrefix префикс имён пользователей веб-сервиса Robot: #ws+XXXXXXXX
+const WebServiceUsernamePrefix = "#ws+"
+
+const (
+	usernameAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.!"
+	passwordLength   = 24
+)
+
+// randomString возвращает строку длины n из символов alphabet
+func randomString(alphabet string, n int) (string, error) {
+	result := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range result {
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		result[i] = alphabet[index.Int64()]
+	}
+	return string(result), nil
+}
+
+// GenerateUsername подбирает свободное имя в стиле Robot, например #ws+Ab3dE6gH
+func GenerateUsername(db *gorm.DB) (string, error) {
+	for attempt := 0; attempt < 10; attempt++ {
+		suffix, err := randomString(usernameAlphabet, 8)
+		if err != nil {
+			return "", err
+		}
+		username := WebServiceUsernamePrefix + suffix
+
+		var count int64
+		if err := db.Model(&User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return username, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a unique username")
+}
+
+// GeneratePassword возвращает случайный пароль для новой или обновлённой учётной записи
+func GeneratePassword() (string, error) {
+	return randomString(passwordAlphabet, passwordLength)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// parseAllowedIP разбирает адрес или подсеть CIDR из списка разрешённых
+func parseAllowedIP(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed IP %q: %w", value, err)
+		}
+		return network, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid allowed IP %q", value)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// AllowedIPList адреса и подсети, с которых пользователю разрешён доступ; пустой список — с любых
+func (u User) AllowedIPList() []string {
+	return strings.Fields(u.AllowedIPs)
+}
+
+// SetAllowedIPs проверяет и сохраняет список разрешённых адресов и подсетей
+func (u *User) SetAllowedIPs(values []string) error {
+	for _, value := range values {
+		if _, err := parseAllowedIP(value); err != nil {
+			return err
+		}
+	}
+	u.AllowedIPs = strings.Join(values, " ")
+	return nil
+}
+
+// AllowsIP сообщает, разрешён ли пользователю доступ с адреса ip
+func (u User) AllowsIP(ip string) bool {
+	allowed := u.AllowedIPList()
+	if len(allowed) == 0 {
+		return true
+	}
+	source := net.ParseIP(ip)
+	if source == nil {
+		return false
+	}
+	for _, value := range allowed {
+		network, err := parseAllowedIP(value)
+		if err == nil && network.Contains(source) {
+			return true
+		}
+	}
+	return false
+}
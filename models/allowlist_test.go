@@ -0,0 +1,57 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetAllowedIPs(t *testing.T) {
+	var user User
+	if err := user.SetAllowedIPs([]string{"203.0.113.10", "198.51.100.0/24", "2001:db8::/32"}); err != nil {
+		t.Fatalf("SetAllowedIPs: %v", err)
+	}
+	if want := []string{"203.0.113.10", "198.51.100.0/24", "2001:db8::/32"}; !reflect.DeepEqual(user.AllowedIPList(), want) {
+		t.Errorf("AllowedIPList = %v, want %v", user.AllowedIPList(), want)
+	}
+
+	// Неверный список не заменяет сохранённый
+	for _, invalid := range []string{"203.0.113.300", "198.51.100.0/33", "example.com"} {
+		if err := user.SetAllowedIPs([]string{"192.0.2.1", invalid}); err == nil {
+			t.Errorf("SetAllowedIPs(%q) accepted", invalid)
+		}
+	}
+	if len(user.AllowedIPList()) != 3 {
+		t.Errorf("AllowedIPList = %v after rejected updates", user.AllowedIPList())
+	}
+
+	if err := user.SetAllowedIPs(nil); err != nil || len(user.AllowedIPList()) != 0 {
+		t.Errorf("clearing the list: %v, %v", user.AllowedIPList(), err)
+	}
+}
+
+func TestAllowsIP(t *testing.T) {
+	var user User
+	if !user.AllowsIP("192.0.2.1") {
+		t.Error("an empty list must allow any address")
+	}
+	if err := user.SetAllowedIPs([]string{"203.0.113.10", "198.51.100.0/24", "2001:db8::/32"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"203.0.113.10":        true,
+		"203.0.113.11":        false,
+		"198.51.100.200":      true,
+		"198.51.101.1":        false,
+		"::ffff:203.0.113.10": true,
+		"2001:db8:1::1":       true,
+		"2001:db9::1":         false,
+		"not an address":      false,
+		"":                    false,
+	}
+	for ip, want := range tests {
+		if got := user.AllowsIP(ip); got != want {
+			t.Errorf("AllowsIP(%q) = %v, want %v", ip, got, want)
+		}
+	}
+}
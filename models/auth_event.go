@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Результаты проверки учётных данных
+const (
+	AuthResultSuccess = "success"
+	AuthResultFailure = "failure"
+)
+
+// Причины результата аутентификации. Клиент получает только общий код ошибки, причина видна в журнале аудита
+const (
+	AuthReasonOK                = "OK"
+	AuthReasonHeaderMissing     = "AUTH_HEADER_MISSING"
+	AuthReasonInvalidFormat     = "INVALID_AUTH_FORMAT"
+	AuthReasonInvalidBase64     = "INVALID_BASE64_ENCODING"
+	AuthReasonInvalidCredFormat = "INVALID_USERNAME_PASSWORD_FORMAT"
+	AuthReasonUnknownUser       = "UNKNOWN_USER"
+	AuthReasonWrongPassword     = "WRONG_PASSWORD"
+	AuthReasonUserDisabled      = "USER_DISABLED"
+	AuthReasonIPNotAllowed      = "IP_NOT_ALLOWED"
+	AuthReasonIPBlocked         = "IP_BLOCKED"
+	AuthReasonDatabaseError     = "DATABASE_ERROR"
+)
+
+// AuthEvent запись журнала аудита аутентификации. Пароль и хеш не сохраняются
+type AuthEvent struct {
+	ID       int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Time     time.Time `gorm:"not null;index" json:"time"`
+	Username string    `gorm:"type:varchar(255);index" json:"username"`
+	UserID   int       `json:"user_id,omitempty"`
+	SourceIP string    `gorm:"type:varchar(45)" json:"source_ip"`
+	Method   string    `gorm:"type:varchar(10)" json:"method"`
+	Path     string    `gorm:"type:varchar(255)" json:"path"`
+	Result   string    `gorm:"type:varchar(16);index" json:"result"`
+	Reason   string    `gorm:"type:varchar(64)" json:"reason"`
+}
+
+// AuthEventFilter условия выборки из журнала аудита; пустые поля не ограничивают выборку
+type AuthEventFilter struct {
+	Username string
+	SourceIP string
+	Result   string
+	Reason   string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// FindAuthEvents возвращает записи аудита от новых к старым
+func FindAuthEvents(db *gorm.DB, filter AuthEventFilter) ([]AuthEvent, error) {
+	query := db.Model(&AuthEvent{})
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.SourceIP != "" {
+		query = query.Where("source_ip = ?", filter.SourceIP)
+	}
+	if filter.Result != "" {
+		query = query.Where("result = ?", filter.Result)
+	}
+	if filter.Reason != "" {
+		query = query.Where("reason = ?", filter.Reason)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("time >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("time <= ?", filter.Until)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	events := []AuthEvent{}
+	if err := query.Order("id DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// PruneAuthEvents удаляет записи аудита сверх keep последних и записи раньше before.
+// Нулевые keep и before не ограничивают. Возвращает число удалённых записей
+func PruneAuthEvents(db *gorm.DB, keep int, before time.Time) (int64, error) {
+	var deleted int64
+	if !before.IsZero() {
+		result := db.Where("time < ?", before).Delete(&AuthEvent{})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	if keep > 0 {
+		// Идентификатор самой новой записи, которая уже не помещается в лимит
+		var ids []int64
+		if err := db.Model(&AuthEvent{}).Order("id DESC").Offset(keep).Limit(1).Pluck("id", &ids).Error; err != nil {
+			return deleted, err
+		}
+		if len(ids) > 0 {
+			result := db.Where("id <= ?", ids[0]).Delete(&AuthEvent{})
+			if result.Error != nil {
+				return deleted, result.Error
+			}
+			deleted += result.RowsAffected
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Product описывает тариф выделенного сервера и доступные для него функции Robot
+type Product struct {
+	Name      string   `json:"name"`
+	Traffic   string   `json:"traffic"`
+	Locations []string `json:"locations"` // Площадки (FSN1, NBG1, HEL1), где доступен продукт
+	Reset     bool     `json:"reset"`
+	Rescue    bool     `json:"rescue"`
+	Vnc       bool     `json:"vnc"`
+	Windows   bool     `json:"windows"`
+	Plesk     bool     `json:"plesk"`
+	Cpanel    bool     `json:"cpanel"`
+	Wol       bool     `json:"wol"`
+	HotSwap   bool     `json:"hot_swap"`
+}
+
+// Location описывает датацентр, в котором может стоять сервер
+type Location struct {
+	Name    string `json:"name"` // Например, FSN1-DC14
+	Site    string `json:"site"` // Например, FSN1
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+var products = map[string]Product{
+	// Устаревшие продукты из примеров документации Robot
+	"DS 3000": {Name: "DS 3000", Traffic: "5 TB", Locations: []string{"NBG1", "FSN1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Plesk: true, Cpanel: true, Wol: true, HotSwap: true},
+	"X5":      {Name: "X5", Traffic: "2 TB", Locations: []string{"NBG1", "FSN1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Plesk: true, Cpanel: true, Wol: true, HotSwap: true},
+	// Актуальная линейка
+	"AX41":      {Name: "AX41", Traffic: "unlimited", Locations: []string{"FSN1", "NBG1", "HEL1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Wol: true},
+	"AX41-NVMe": {Name: "AX41-NVMe", Traffic: "unlimited", Locations: []string{"FSN1", "NBG1", "HEL1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Wol: true},
+	"AX52":      {Name: "AX52", Traffic: "unlimited", Locations: []string{"FSN1", "NBG1", "HEL1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Wol: true},
+	"AX102":     {Name: "AX102", Traffic: "unlimited", Locations: []string{"FSN1", "NBG1", "HEL1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Wol: true},
+	"EX44":      {Name: "EX44", Traffic: "unlimited", Locations: []string{"FSN1", "HEL1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Wol: true},
+	"EX101":     {Name: "EX101", Traffic: "unlimited", Locations: []string{"FSN1", "NBG1"}, Reset: true, Rescue: true, Vnc: true, Windows: true, Wol: true},
+	"SX64":      {Name: "SX64", Traffic: "unlimited", Locations: []string{"FSN1"}, Reset: true, Rescue: true, Vnc: true, Wol: true, HotSwap: true},
+	"SX134":     {Name: "SX134", Traffic: "unlimited", Locations: []string{"FSN1", "HEL1"}, Reset: true, Rescue: true, Vnc: true, Wol: true, HotSwap: true},
+}
+
+var locations = map[string]Location{
+	"FSN1-DC1":  {Name: "FSN1-DC1", Site: "FSN1", City: "Falkenstein", Country: "DE"},
+	"FSN1-DC5":  {Name: "FSN1-DC5", Site: "FSN1", City: "Falkenstein", Country: "DE"},
+	"FSN1-DC10": {Name: "FSN1-DC10", Site: "FSN1", City: "Falkenstein", Country: "DE"},
+	"FSN1-DC14": {Name: "FSN1-DC14", Site: "FSN1", City: "Falkenstein", Country: "DE"},
+	"FSN1-DC18": {Name: "FSN1-DC18", Site: "FSN1", City: "Falkenstein", Country: "DE"},
+	"NBG1-DC1":  {Name: "NBG1-DC1", Site: "NBG1", City: "Nuremberg", Country: "DE"},
+	"NBG1-DC3":  {Name: "NBG1-DC3", Site: "NBG1", City: "Nuremberg", Country: "DE"},
+	"NBG1-DC4":  {Name: "NBG1-DC4", Site: "NBG1", City: "Nuremberg", Country: "DE"},
+	"HEL1-DC2":  {Name: "HEL1-DC2", Site: "HEL1", City: "Helsinki", Country: "FI"},
+	"HEL1-DC6":  {Name: "HEL1-DC6", Site: "HEL1", City: "Helsinki", Country: "FI"},
+}
+
+// GetProduct возвращает продукт из каталога по имени
+func GetProduct(name string) (Product, bool) {
+	product, ok := products[name]
+	return product, ok
+}
+
+// GetLocation возвращает датацентр из каталога по имени
+func GetLocation(name string) (Location, bool) {
+	location, ok := locations[name]
+	return location, ok
+}
+
+// GetAllProducts возвращает все продукты каталога, отсортированные по имени
+func GetAllProducts() []Product {
+	result := make([]Product, 0, len(products))
+	for _, product := range products {
+		result = append(result, product)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// GetAllLocations возвращает все датацентры каталога, отсортированные по имени
+func GetAllLocations() []Location {
+	result := make([]Location, 0, len(locations))
+	for _, location := range locations {
+		result = append(result, location)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// ValidateCatalog проверяет, что продукт и датацентр сервера есть в каталоге и совместимы
+func (s *Server) ValidateCatalog() error {
+	product, ok := GetProduct(s.Product)
+	if !ok {
+		return fmt.Errorf("server %d: unknown product %q", s.ServerNumber, s.Product)
+	}
+	location, ok := GetLocation(s.DC)
+	if !ok {
+		return fmt.Errorf("server %d: unknown datacenter %q", s.ServerNumber, s.DC)
+	}
+	for _, site := range product.Locations {
+		if site == location.Site {
+			return nil
+		}
+	}
+	return fmt.Errorf("server %d: product %q is not available in %s (available in %s)",
+		s.ServerNumber, s.Product, s.DC, strings.Join(product.Locations, ", "))
+}
+
+// ApplyCatalog проверяет сервер по каталогу и выставляет трафик и флаги возможностей из продукта
+func (s *Server) ApplyCatalog() error {
+	if err := s.ValidateCatalog(); err != nil {
+		return err
+	}
+
+	product, _ := GetProduct(s.Product)
+	s.Traffic = product.Traffic
+	s.Reset = product.Reset
+	s.Rescue = product.Rescue
+	s.Vnc = product.Vnc
+	s.Windows = product.Windows
+	s.Plesk = product.Plesk
+	s.Cpanel = product.Cpanel
+	s.Wol = product.Wol
+	s.HotSwap = product.HotSwap
+	return nil
+}
+
+// ValidateServers проверяет уже загруженные данные (например, импортированный дамп) по каталогу
+func ValidateServers(db *gorm.DB) ([]error, error) {
+	var servers []Server
+	if err := db.Find(&servers).Error; err != nil {
+		return nil, err
+	}
+
+	var problems []error
+	for _, server := range servers {
+		if err := server.ValidateCatalog(); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		// Флаги, выставленные вручную, должны совпадать с продуктом
+		expected := server
+		_ = expected.ApplyCatalog()
+		if expected.Traffic != server.Traffic ||
+			expected.Reset != server.Reset ||
+			expected.Rescue != server.Rescue ||
+			expected.Vnc != server.Vnc ||
+			expected.Windows != server.Windows ||
+			expected.Plesk != server.Plesk ||
+			expected.Cpanel != server.Cpanel ||
+			expected.Wol != server.Wol ||
+			expected.HotSwap != server.HotSwap {
+			problems = append(problems, fmt.Errorf("server %d: traffic or capability flags differ from product %q", server.ServerNumber, server.Product))
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"hetzner-api-emulator/database"
+	"hetzner-api-emulator/migrations"
+	"hetzner-api-emulator/models"
+)
+
+func TestValidateCatalog(t *testing.T) {
+	tests := []struct {
+		product, dc string
+		wantErr     string
+	}{
+		{product: "AX41", dc: "FSN1-DC14"},
+		{product: "DS 3000", dc: "NBG1-DC1"},
+		{product: "EX44", dc: "HEL1-DC2"},
+		{product: "AX1000", dc: "FSN1-DC14", wantErr: `unknown product "AX1000"`},
+		{product: "AX41", dc: "ASH-DC1", wantErr: `unknown datacenter "ASH-DC1"`},
+		{product: "SX64", dc: "HEL1-DC2", wantErr: `product "SX64" is not available in HEL1-DC2 (available in FSN1)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.product+"/"+tt.dc, func(t *testing.T) {
+			server := models.Server{ServerNumber: 321, Product: tt.product, DC: tt.dc}
+			err := server.ValidateCatalog()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCatalog: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.HasPrefix(err.Error(), "server 321: ") {
+				t.Errorf("ValidateCatalog = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyCatalog(t *testing.T) {
+	// Флаги, заданные вручную, заменяются флагами продукта
+	server := models.Server{ServerNumber: 321, Product: "SX64", DC: "FSN1-DC18", Traffic: "5 TB", Windows: true, Plesk: true}
+	if err := server.ApplyCatalog(); err != nil {
+		t.Fatalf("ApplyCatalog: %v", err)
+	}
+	if server.Traffic != "unlimited" || !server.HotSwap || !server.Reset || server.Windows || server.Plesk || server.Cpanel {
+		t.Errorf("server after ApplyCatalog = %+v", server)
+	}
+
+	unknown := models.Server{ServerNumber: 421, Product: "AX1000", DC: "FSN1-DC14", Traffic: "5 TB"}
+	if err := unknown.ApplyCatalog(); err == nil || unknown.Traffic != "5 TB" {
+		t.Errorf("ApplyCatalog for an unknown product = %v, traffic %q, want an error and no changes", err, unknown.Traffic)
+	}
+}
+
+func TestValidateServers(t *testing.T) {
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	valid := models.Server{UserID: 1, ServerNumber: 321, ServerName: "ok", Product: "AX41", DC: "FSN1-DC14"}
+	if err := valid.ApplyCatalog(); err != nil {
+		t.Fatal(err)
+	}
+	// Так выглядят записи из старого дампа: продукт вне каталога и флаги, не совпадающие с продуктом
+	flags := valid
+	flags.ServerNumber, flags.Plesk = 421, true
+	unknown := models.Server{UserID: 1, ServerNumber: 521, ServerName: "old", Product: "EX40", DC: "FSN1-DC14"}
+	for _, server := range []models.Server{valid, flags, unknown} {
+		if err := db.Create(&server).Error; err != nil {
+			t.Fatalf("create server %d: %v", server.ServerNumber, err)
+		}
+	}
+
+	problems, err := models.ValidateServers(db)
+	if err != nil {
+		t.Fatalf("ValidateServers: %v", err)
+	}
+	if len(problems) != 2 ||
+		!strings.Contains(problems[0].Error(), `server 421: traffic or capability flags differ from product "AX41"`) ||
+		!strings.Contains(problems[1].Error(), `server 521: unknown product "EX40"`) {
+		t.Errorf("problems = %v", problems)
+	}
+}
@@ -1,9 +1,6 @@
 package models
 
 import (
-	"log"
-	"os"
-	"reflect"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,7 +9,7 @@ import (
 type IP struct {
     ID        int       `gorm:"primaryKey;autoIncrement"`
     ServerID  int       `gorm:"not null;index"`
-    IPAddress string    `gorm:"type:varchar(15);not null"`
+    IPAddress string    `gorm:"type:varchar(45);not null"` // IPv4 или IPv6
     Mask      string    `gorm:"type:varchar(15);not null"`
 }
 
@@ -43,46 +40,15 @@ type Server struct {
     Reserved              bool       `gorm:"default:false"`
     CancellationDate      *time.Time `gorm:"column:cancellation_date"`
 	CancellationReason string `gorm:"type:varchar(255);"`
+    DeletedAt             gorm.DeletedAt `gorm:"index"` // Сервер удалён после даты отмены (режим lifecycle mark)
 }
 
 type User struct {
     ID        int       `gorm:"primaryKey;autoIncrement"`
     Username  string    `gorm:"uniqueIndex;type:varchar(255)"`
     Password  string    `gorm:"type:varchar(255)"`
+    Disabled  bool      `gorm:"default:false"` // Отключённый пользователь не может аутентифицироваться
+    AllowedIPs string   `gorm:"type:varchar(1024)"` // Разрешённые адреса и подсети через пробел; пусто — любые
+    Scopes    string    `gorm:"type:varchar(1024)"` // Области доступа через пробел, например "server:read reset:write"; пусто — без ограничений
     CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
-
-// Migrate функция для миграции моделей в зависимости от типа базы данных
-// Migrate функция для миграции моделей в зависимости от типа базы данных
-func Migrate(db *gorm.DB) {
-	// Получаем тип базы данных из переменной окружения
-	dbType := os.Getenv("DB_CONNECTION")
-	if dbType == "" {
-		log.Fatal("DB_CONNECTION environment variable not set")
-	}
-
-	// Получаем список всех моделей
-	models := []interface{}{
-		&User{},
-		&Server{},
-		&IP{},
-	}
-
-	// Выполняем миграцию для каждой модели
-	for _, model := range models {
-		if dbType == "mysql" || dbType == "postgres" {
-			// Проверяем существование таблицы перед миграцией
-			if !db.Migrator().HasTable(model) {
-				log.Printf("Table for model %s doesn't exist, creating it", reflect.TypeOf(model).String())
-			}
-
-			// Выполняем миграцию
-			if err := db.AutoMigrate(model); err != nil {
-				log.Fatalf("Migration failed for %s: %v", reflect.TypeOf(model).String(), err)
-			}
-		} else {
-			log.Fatalf("Unsupported DB type: %v", dbType)
-		}
-	}
-}
-
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// IsDuplicateKey сообщает, что ошибка базы — нарушение уникального индекса или первичного ключа.
+// Коды ошибок у драйверов разные, поэтому ошибка переводится диалектом базы db
+func IsDuplicateKey(db *gorm.DB, err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
+}
+
+// SpecError ошибка в описании ресурса: неизвестный продукт или пользователь, неверная дата и т.п.
+// В отличие от ошибок базы её текст можно вернуть клиенту
+type SpecError struct {
+	Err error
+}
+
+func (e *SpecError) Error() string { return e.Err.Error() }
+
+func (e *SpecError) Unwrap() error { return e.Err }
+
+// IsSpecError сообщает, что ошибка вызвана описанием ресурса, а не базой
+func IsSpecError(err error) bool {
+	var specErr *SpecError
+	return errors.As(err, &specErr)
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Права доступа к группе маршрутов
+const (
+	ScopeRead  = "read"  // GET и HEAD
+	ScopeWrite = "write" // POST, PUT, PATCH и DELETE
+	ScopeAll   = "*"
+)
+
+// ParseScope проверяет область доступа вида <группа>:<право>, например server:read, reset:write или storagebox:*.
+// Группа — первый сегмент пути Robot API (server, reset, firewall, ...); "*" — все группы и все права
+func ParseScope(scope string) (group, access string, err error) {
+	if scope == ScopeAll {
+		return ScopeAll, ScopeAll, nil
+	}
+	group, access, ok := strings.Cut(scope, ":")
+	if !ok || group == "" {
+		return "", "", fmt.Errorf("invalid scope %q, expected <group>:<read|write|*>", scope)
+	}
+	for _, r := range group {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '*') {
+			return "", "", fmt.Errorf("invalid scope %q: group must be lowercase", scope)
+		}
+	}
+	if access != ScopeRead && access != ScopeWrite && access != ScopeAll {
+		return "", "", fmt.Errorf("invalid scope %q: access must be read, write or *", scope)
+	}
+	return group, access, nil
+}
+
+// RequiredScope область доступа, нужная для запроса method к группе маршрутов group
+func RequiredScope(method, group string) string {
+	access := ScopeWrite
+	if method == http.MethodGet || method == http.MethodHead {
+		access = ScopeRead
+	}
+	return group + ":" + access
+}
+
+// ScopeList области доступа пользователя; пустой список означает полный доступ
+func (u User) ScopeList() []string {
+	return strings.Fields(u.Scopes)
+}
+
+// SetScopes проверяет и сохраняет области доступа в модели пользователя
+func (u *User) SetScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if _, _, err := ParseScope(scope); err != nil {
+			return err
+		}
+	}
+	u.Scopes = strings.Join(scopes, " ")
+	return nil
+}
+
+// HasScope сообщает, разрешает ли набор scopes запрос, для которого нужна область required.
+// Пустой набор — пользователь без ограничений
+func HasScope(scopes []string, required string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	requiredGroup, requiredAccess, err := ParseScope(required)
+	if err != nil {
+		return false
+	}
+	for _, scope := range scopes {
+		group, access, err := ParseScope(scope)
+		if err != nil {
+			continue
+		}
+		if (group == ScopeAll || group == requiredGroup) && (access == ScopeAll || access == requiredAccess) {
+			return true
+		}
+	}
+	return false
+}
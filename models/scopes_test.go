@@ -0,0 +1,99 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		scope   string
+		group   string
+		access  string
+		wantErr bool
+	}{
+		{scope: "*", group: "*", access: "*"},
+		{scope: "server:read", group: "server", access: "read"},
+		{scope: "reset:write", group: "reset", access: "write"},
+		{scope: "storagebox:*", group: "storagebox", access: "*"},
+		{scope: "*:read", group: "*", access: "read"},
+		{scope: "server", wantErr: true},
+		{scope: ":read", wantErr: true},
+		{scope: "server:admin", wantErr: true},
+		{scope: "Server:read", wantErr: true},
+		{scope: "server:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			group, access, err := ParseScope(tt.scope)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if group != tt.group || access != tt.access {
+				t.Errorf("got (%q, %q), want (%q, %q)", group, access, tt.group, tt.access)
+			}
+		})
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method, group, want string
+	}{
+		{http.MethodGet, "server", "server:read"},
+		{http.MethodHead, "server", "server:read"},
+		{http.MethodPost, "reset", "reset:write"},
+		{http.MethodPut, "firewall", "firewall:write"},
+		{http.MethodDelete, "server", "server:write"},
+	}
+	for _, tt := range tests {
+		if got := RequiredScope(tt.method, tt.group); got != tt.want {
+			t.Errorf("RequiredScope(%s, %s) = %q, want %q", tt.method, tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		required string
+		want     bool
+	}{
+		{"no scopes means full access", nil, "reset:write", true},
+		{"exact match", []string{"server:read"}, "server:read", true},
+		{"read does not allow write", []string{"server:read"}, "server:write", false},
+		{"other group", []string{"server:write"}, "reset:write", false},
+		{"any access in group", []string{"server:*"}, "server:write", true},
+		{"read in every group", []string{"*:read"}, "firewall:read", true},
+		{"read in every group does not allow write", []string{"*:read"}, "firewall:write", false},
+		{"everything", []string{"*"}, "reset:write", true},
+		{"one of several", []string{"server:read", "reset:write"}, "reset:write", true},
+		{"invalid scope is ignored", []string{"bogus"}, "server:read", false},
+		{"invalid required scope", []string{"server:read"}, "server", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasScope(tt.scopes, tt.required); got != tt.want {
+				t.Errorf("HasScope(%v, %q) = %v, want %v", tt.scopes, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserSetScopes(t *testing.T) {
+	var user User
+	if err := user.SetScopes([]string{"server:read", "reset:write"}); err != nil {
+		t.Fatalf("SetScopes: %v", err)
+	}
+	if got := user.ScopeList(); len(got) != 2 || got[0] != "server:read" || got[1] != "reset:write" {
+		t.Errorf("ScopeList = %v", got)
+	}
+
+	if err := user.SetScopes([]string{"server:read", "nope"}); err == nil {
+		t.Error("invalid scope accepted")
+	}
+	if got := user.ScopeList(); len(got) != 2 {
+		t.Errorf("failed SetScopes changed scopes to %v", got)
+	}
+}
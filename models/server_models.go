@@ -41,8 +41,66 @@ type ServerResponse struct {
 	} `json:"server"`
 }
 
+// ServerResponseShort элемент списка GET /server: без флагов возможностей
+type ServerResponseShort struct {
+	Server struct {
+		ServerIP      string   `json:"server_ip"`
+		ServerIPv6Net string   `json:"server_ipv6_net"`
+		ServerNumber  int      `json:"server_number"`
+		ServerName    string   `json:"server_name"`
+		Product       string   `json:"product"`
+		DC            string   `json:"dc"`
+		Traffic       string   `json:"traffic"`
+		Status        string   `json:"status"`
+		Cancelled     bool     `json:"cancelled"`
+		PaidUntil     string   `json:"paid_until"`
+		IP            []string `json:"ip"`
+		Subnet        []Subnet `json:"subnet"`
+	} `json:"server"`
+}
+
 // Subnet структура для представления подсети
 type Subnet struct {
 	IP   string `json:"ip"`
 	Mask string `json:"mask"`
-}
\ No newline at end of file
+}
+// CancellationResponse ответ GET/POST /server/{server-number}/cancellation. Даты в формате yyyy-MM-dd
+type CancellationResponse struct {
+	Cancellation struct {
+		ServerIP                 string      `json:"server_ip"`
+		ServerIPv6Net            string      `json:"server_ipv6_net"`
+		ServerNumber             int         `json:"server_number"`
+		ServerName               string      `json:"server_name"`
+		EarliestCancellationDate string      `json:"earliest_cancellation_date"`
+		Cancelled                bool        `json:"cancelled"`
+		ReservationPossible      bool        `json:"reservation_possible"`
+		Reserved                 bool        `json:"reserved"`
+		CancellationDate         *string     `json:"cancellation_date"`
+		CancellationReason       interface{} `json:"cancellation_reason"` // Список причин, пока сервер не отменён, иначе строка или null
+	} `json:"cancellation"`
+}
+
+// NewCancellationResponse заполняет ответ об отмене по данным сервера
+func NewCancellationResponse(server Server, earliestCancellationDate string) CancellationResponse {
+	var response CancellationResponse
+	response.Cancellation.ServerIP = server.ServerIP
+	response.Cancellation.ServerIPv6Net = server.ServerIPv6Net
+	response.Cancellation.ServerNumber = server.ServerNumber
+	response.Cancellation.ServerName = server.ServerName
+	response.Cancellation.EarliestCancellationDate = earliestCancellationDate
+	response.Cancellation.Cancelled = server.Cancelled
+	response.Cancellation.ReservationPossible = server.ReservationPossible
+	response.Cancellation.Reserved = server.Reserved
+
+	if server.CancellationDate != nil {
+		cancellationDate := server.CancellationDate.Format("2006-01-02")
+		response.Cancellation.CancellationDate = &cancellationDate
+	}
+
+	if !server.Cancelled {
+		response.Cancellation.CancellationReason = GetAllCancellationReasons() // Массив причин
+	} else if server.CancellationReason != "" {
+		response.Cancellation.CancellationReason = server.CancellationReason // Причина из базы данных
+	}
+	return response
+}
@@ -0,0 +1,307 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// UserSpec описывает пользователя в JSON/YAML (административный API, фикстуры)
+type UserSpec struct {
+	ID           int        `json:"id,omitempty" yaml:"id,omitempty"`
+	Username     string     `json:"username" yaml:"username"`
+	Password     string     `json:"password,omitempty" yaml:"password,omitempty"`           // Пароль в открытом виде: на вход или один раз в ответ, если он сгенерирован
+	PasswordHash string     `json:"password_hash,omitempty" yaml:"password_hash,omitempty"` // bcrypt-хеш вместо пароля: так выгрузка переносит пароли, не раскрывая их
+	Disabled     bool       `json:"disabled" yaml:"disabled,omitempty"`
+	Scopes       []string   `json:"scopes" yaml:"scopes,omitempty"`           // Пустой список — полный доступ
+	AllowedIPs   []string   `json:"allowed_ips" yaml:"allowed_ips,omitempty"` // Пустой список — доступ с любых адресов
+	CreatedAt    *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+}
+
+// IPSpec описывает IP-адрес или подсеть сервера
+type IPSpec struct {
+	ID           int    `json:"id,omitempty" yaml:"id,omitempty"`
+	ServerNumber int    `json:"server_number,omitempty" yaml:"server_number,omitempty"`
+	IP           string `json:"ip" yaml:"ip"`
+	Mask         string `json:"mask" yaml:"mask"`
+}
+
+// ServerSpec описывает сервер. Трафик и флаги возможностей выводятся из продукта и только отдаются наружу
+type ServerSpec struct {
+	ServerNumber        int      `json:"server_number" yaml:"server_number"`
+	UserID              int      `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Username            string   `json:"username,omitempty" yaml:"username,omitempty"` // Альтернатива user_id
+	ServerName          string   `json:"server_name" yaml:"server_name"`
+	ServerIP            string   `json:"server_ip" yaml:"server_ip"`
+	ServerIPv6Net       string   `json:"server_ipv6_net" yaml:"server_ipv6_net"`
+	Product             string   `json:"product" yaml:"product"`
+	DC                  string   `json:"dc" yaml:"dc"`
+	Status              string   `json:"status" yaml:"status"`
+	Cancelled           bool     `json:"cancelled" yaml:"cancelled"`
+	PaidUntil           string   `json:"paid_until,omitempty" yaml:"paid_until,omitempty"` // yyyy-MM-dd
+	LinkedStoragebox    int      `json:"linked_storagebox" yaml:"linked_storagebox"`
+	ReservationPossible bool     `json:"reservation_possible" yaml:"reservation_possible"`
+	Reserved            bool     `json:"reserved" yaml:"reserved"`
+	CancellationDate    string   `json:"cancellation_date,omitempty" yaml:"cancellation_date,omitempty"` // yyyy-MM-dd
+	CancellationReason  string   `json:"cancellation_reason,omitempty" yaml:"cancellation_reason,omitempty"`
+	IPs                 []IPSpec `json:"ips" yaml:"ips"`
+
+	GoneAt  string `json:"gone_at,omitempty" yaml:"-"` // Когда сервер был снят после даты отмены
+	Traffic string `json:"traffic,omitempty" yaml:"-"`
+	Reset   bool   `json:"reset" yaml:"-"`
+	Rescue  bool   `json:"rescue" yaml:"-"`
+	Vnc     bool   `json:"vnc" yaml:"-"`
+	Windows bool   `json:"windows" yaml:"-"`
+	Plesk   bool   `json:"plesk" yaml:"-"`
+	Cpanel  bool   `json:"cpanel" yaml:"-"`
+	Wol     bool   `json:"wol" yaml:"-"`
+	HotSwap bool   `json:"hot_swap" yaml:"-"`
+}
+
+// bcryptCost стоимость bcrypt для новых паролей
+var bcryptCost = bcrypt.DefaultCost
+
+// SetBcryptCost меняет стоимость bcrypt для новых паролей. В тестовых окружениях достаточно bcrypt.MinCost
+func SetBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost %d out of range %d-%d", cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	bcryptCost = cost
+	return nil
+}
+
+// HashPassword хеширует пароль так же, как при регистрации пользователя
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// NewUserSpec формирует описание пользователя без пароля
+func NewUserSpec(user User) UserSpec {
+	createdAt := user.CreatedAt
+	return UserSpec{
+		ID:         user.ID,
+		Username:   user.Username,
+		Disabled:   user.Disabled,
+		Scopes:     user.ScopeList(),
+		AllowedIPs: user.AllowedIPList(),
+		CreatedAt:  &createdAt,
+	}
+}
+
+// NewServerSpec формирует описание сервера вместе с его IP-адресами
+func NewServerSpec(server Server) ServerSpec {
+	spec := ServerSpec{
+		ServerNumber:        server.ServerNumber,
+		UserID:              server.UserID,
+		ServerName:          server.ServerName,
+		ServerIP:            server.ServerIP,
+		ServerIPv6Net:       server.ServerIPv6Net,
+		Product:             server.Product,
+		DC:                  server.DC,
+		Status:              server.Status,
+		Cancelled:           server.Cancelled,
+		LinkedStoragebox:    server.LinkedStoragebox,
+		ReservationPossible: server.ReservationPossible,
+		Reserved:            server.Reserved,
+		CancellationReason:  server.CancellationReason,
+		IPs:                 []IPSpec{},
+		Traffic:             server.Traffic,
+		Reset:               server.Reset,
+		Rescue:              server.Rescue,
+		Vnc:                 server.Vnc,
+		Windows:             server.Windows,
+		Plesk:               server.Plesk,
+		Cpanel:              server.Cpanel,
+		Wol:                 server.Wol,
+		HotSwap:             server.HotSwap,
+	}
+	if server.PaidUntil != nil {
+		spec.PaidUntil = server.PaidUntil.Format("2006-01-02")
+	}
+	if server.CancellationDate != nil {
+		spec.CancellationDate = server.CancellationDate.Format("2006-01-02")
+	}
+	if server.DeletedAt.Valid {
+		spec.GoneAt = server.DeletedAt.Time.UTC().Format(time.RFC3339)
+	}
+	for _, ip := range server.IPs {
+		spec.IPs = append(spec.IPs, IPSpec{
+			ID:           ip.ID,
+			ServerNumber: server.ServerNumber,
+			IP:           ip.IPAddress,
+			Mask:         ip.Mask,
+		})
+	}
+	return spec
+}
+
+// Apply переносит поля описания в модель сервера (кроме IP-адресов и владельца)
+func (spec ServerSpec) Apply(server *Server) error {
+	paidUntil, err := parseSpecDate("paid_until", spec.PaidUntil)
+	if err != nil {
+		return err
+	}
+	cancellationDate, err := parseSpecDate("cancellation_date", spec.CancellationDate)
+	if err != nil {
+		return err
+	}
+
+	server.ServerNumber = spec.ServerNumber
+	server.ServerName = spec.ServerName
+	server.ServerIP = spec.ServerIP
+	server.ServerIPv6Net = spec.ServerIPv6Net
+	server.Product = spec.Product
+	server.DC = spec.DC
+	server.Status = spec.Status
+	server.Cancelled = spec.Cancelled
+	server.PaidUntil = paidUntil
+	server.LinkedStoragebox = spec.LinkedStoragebox
+	server.ReservationPossible = spec.ReservationPossible
+	server.Reserved = spec.Reserved
+	server.CancellationDate = cancellationDate
+	server.CancellationReason = spec.CancellationReason
+	if server.Status == "" {
+		server.Status = "ready"
+	}
+
+	// Проверяем по каталогу до записи в базу и выставляем трафик и флаги возможностей из продукта.
+	// Это делают только явные пути записи описания сервера: переименование или отмена сервера с продуктом
+	// вне каталога (например, из старого дампа) не должны ломаться
+	return server.ApplyCatalog()
+}
+
+// parseSpecDate разбирает дату в формате yyyy-MM-dd, пустая строка означает отсутствие даты
+func parseSpecDate(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q, expected yyyy-MM-dd", field, value)
+	}
+	return &parsed, nil
+}
+
+// UpsertServer создаёт или обновляет сервер по ServerNumber и заменяет его IP-адреса описанными в spec.
+// IP-адрес без явного ID сохраняет идентификатор, который уже был у того же адреса этого сервера.
+// Ошибки описания возвращаются как *SpecError, остальные — ошибки базы
+func UpsertServer(db *gorm.DB, spec ServerSpec) (*Server, error) {
+	var server Server
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Ищем в том числе снятые серверы: повторная загрузка возвращает их обратно
+		if err := tx.Unscoped().Where("server_number = ?", spec.ServerNumber).Limit(1).Find(&server).Error; err != nil {
+			return err
+		}
+
+		userID := spec.UserID
+		if userID == 0 && spec.Username != "" {
+			var owner User
+			if err := tx.Where("username = ?", spec.Username).First(&owner).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return &SpecError{fmt.Errorf("server %d: user %q not found", spec.ServerNumber, spec.Username)}
+				}
+				return err
+			}
+			userID = owner.ID
+		}
+		if userID == 0 {
+			return &SpecError{fmt.Errorf("server %d: user_id or username is required", spec.ServerNumber)}
+		}
+		server.UserID = userID
+
+		if err := spec.Apply(&server); err != nil {
+			return &SpecError{err}
+		}
+		server.IPs = nil
+		server.DeletedAt = gorm.DeletedAt{}
+		if err := tx.Unscoped().Save(&server).Error; err != nil {
+			return err
+		}
+
+		// Повторная загрузка тех же фикстур не должна менять идентификаторы, по которым к адресам обращаются клиенты
+		var existing []IP
+		if err := tx.Where("server_id = ?", server.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+		existingIDs := make(map[string]int, len(existing))
+		for _, ip := range existing {
+			existingIDs[ip.IPAddress] = ip.ID
+		}
+		explicitIDs := make(map[int]bool, len(spec.IPs))
+		for _, ipSpec := range spec.IPs {
+			explicitIDs[ipSpec.ID] = true
+		}
+
+		if err := tx.Where("server_id = ?", server.ID).Delete(&IP{}).Error; err != nil {
+			return err
+		}
+		for _, ipSpec := range spec.IPs {
+			id := ipSpec.ID
+			if id == 0 && !explicitIDs[existingIDs[ipSpec.IP]] {
+				id = existingIDs[ipSpec.IP]
+				delete(existingIDs, ipSpec.IP)
+			}
+			ip := IP{ID: id, ServerID: server.ID, IPAddress: ipSpec.IP, Mask: ipSpec.Mask}
+			if err := tx.Create(&ip).Error; err != nil {
+				return err
+			}
+			server.IPs = append(server.IPs, ip)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// UpsertUser создаёт или обновляет пользователя по Username, пароль хешируется
+func UpsertUser(db *gorm.DB, spec UserSpec) (*User, error) {
+	if spec.Username == "" || (spec.Password == "" && spec.PasswordHash == "") {
+		return nil, fmt.Errorf("user %q: username and password or password_hash are required", spec.Username)
+	}
+	if spec.Password == "" {
+		if _, err := bcrypt.Cost([]byte(spec.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("user %q: invalid password_hash: %w", spec.Username, err)
+		}
+	}
+
+	var user User
+	if err := db.Where("username = ?", spec.Username).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+
+	// Не перехешируем пароль, если он не изменился, чтобы повторная загрузка ничего не меняла
+	if spec.Password == "" {
+		user.Password = spec.PasswordHash
+	} else if user.ID == 0 || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(spec.Password)) != nil {
+		hashedPassword, err := HashPassword(spec.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = hashedPassword
+	}
+	if user.ID == 0 {
+		user.ID = spec.ID
+	}
+	user.Username = spec.Username
+	user.Disabled = spec.Disabled
+	if err := user.SetScopes(spec.Scopes); err != nil {
+		return nil, fmt.Errorf("user %q: %w", spec.Username, err)
+	}
+	if err := user.SetAllowedIPs(spec.AllowedIPs); err != nil {
+		return nil, fmt.Errorf("user %q: %w", spec.Username, err)
+	}
+
+	if err := db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
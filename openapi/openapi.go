@@ -0,0 +1,251 @@
+// Package openapi содержит описание OpenAPI 3 для маршрутов Robot, которые реализует эмулятор,
+// и проверку запросов по нему для строгого режима
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec возвращает документ OpenAPI в формате JSON
+func Spec() []byte {
+	return spec
+}
+
+// Document часть OpenAPI 3, нужная для проверки запросов
+type Document struct {
+	Paths map[string]map[string]*Operation `json:"paths"`
+}
+
+// Operation операция над путём
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []Parameter  `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// Parameter параметр пути или строки запроса
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody тело запроса
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType схема тела для одного типа содержимого
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema схема значения
+type Schema struct {
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Enum       []string           `json:"enum"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+}
+
+// Route операция вместе с методом и путём в формате Gin (/server/:server-number)
+type Route struct {
+	Method    string
+	Path      string
+	Operation *Operation
+}
+
+// Validator проверяет запросы по описанию
+type Validator struct {
+	routes map[string]Route
+}
+
+// Load разбирает встроенный документ
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi.json: %w", err)
+	}
+	return &doc, nil
+}
+
+// Routes возвращает все операции описания, отсортированные по пути и методу
+func (d *Document) Routes() []Route {
+	var routes []Route
+	for path, item := range d.Paths {
+		for method, operation := range item {
+			routes = append(routes, Route{Method: strings.ToUpper(method), Path: ginPath(path), Operation: operation})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// NewValidator создаёт проверку запросов по встроенному описанию
+func NewValidator() (*Validator, error) {
+	doc, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	validator := &Validator{routes: map[string]Route{}}
+	for _, route := range doc.Routes() {
+		validator.routes[route.Method+" "+route.Path] = route
+	}
+	return validator, nil
+}
+
+// ginPath переводит /server/{server-number} в /server/:server-number
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + strings.Trim(segment, "{}")
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Request то, что проверяется в запросе
+type Request struct {
+	Method     string
+	Route      string            // Маршрут Gin, например /server/:server-number
+	PathParams map[string]string // Значения параметров пути
+	Query      url.Values
+	Form       url.Values
+}
+
+// Validate проверяет запрос и возвращает отсутствующие и некорректные параметры.
+// found=false означает, что операции нет в описании и проверять нечего
+func (v *Validator) Validate(req Request) (missing, invalid []string, found bool) {
+	route, ok := v.routes[req.Method+" "+req.Route]
+	if !ok {
+		return nil, nil, false
+	}
+	operation := route.Operation
+
+	// Параметры пути и строки запроса
+	knownQuery := map[string]bool{}
+	for _, parameter := range operation.Parameters {
+		var values []string
+		switch parameter.In {
+		case "path":
+			if value, ok := req.PathParams[parameter.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			knownQuery[parameter.Name] = true
+			values = req.Query[parameter.Name]
+		default:
+			continue
+		}
+		if len(values) == 0 {
+			if parameter.Required {
+				missing = append(missing, parameter.Name)
+			}
+			continue
+		}
+		if !valuesMatch(parameter.Schema, values) {
+			invalid = append(invalid, parameter.Name)
+		}
+	}
+	for name := range req.Query {
+		if !knownQuery[name] {
+			invalid = append(invalid, name)
+		}
+	}
+
+	// Параметры формы
+	var body *Schema
+	if operation.RequestBody != nil {
+		body = operation.RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	}
+	if body != nil {
+		for _, name := range body.Required {
+			if len(req.Form[name]) == 0 {
+				missing = append(missing, name)
+			}
+		}
+	}
+	for name, values := range req.Form {
+		var property *Schema
+		if body != nil {
+			property = body.Properties[name]
+		}
+		if property == nil || !valuesMatch(property, values) {
+			invalid = append(invalid, name)
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(invalid)
+	return missing, invalid, true
+}
+
+// valuesMatch проверяет значения параметра по схеме
+func valuesMatch(schema *Schema, values []string) bool {
+	if schema == nil {
+		return true
+	}
+	if len(values) > 1 && schema.Type != "array" {
+		return false
+	}
+	for _, value := range values {
+		if !valueMatches(schema, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func valueMatches(schema *Schema, value string) bool {
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return false
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return false
+		}
+	case "boolean":
+		if value != "true" && value != "false" {
+			return false
+		}
+	case "string":
+		if value == "" {
+			return false
+		}
+		if schema.Format == "date" {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return false
+			}
+		}
+	}
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if value == allowed {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package openapi
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestGinPath(t *testing.T) {
+	tests := map[string]string{
+		"/server":                                     "/server",
+		"/server/{server-number}":                     "/server/:server-number",
+		"/server/{server-number}/cancellation":        "/server/:server-number/cancellation",
+		"/storagebox/{storagebox-id}/snapshot/{name}": "/storagebox/:storagebox-id/snapshot/:name",
+	}
+	for path, want := range tests {
+		if got := ginPath(path); got != want {
+			t.Errorf("ginPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator: %v", err)
+	}
+	server := map[string]string{"server-number": "321"}
+
+	tests := []struct {
+		name                     string
+		request                  Request
+		wantMissing, wantInvalid []string
+	}{
+		{
+			name:    "valid list",
+			request: Request{Method: "GET", Route: "/server"},
+		},
+		{
+			name:    "valid rename",
+			request: Request{Method: "POST", Route: "/server/:server-number", PathParams: server, Form: url.Values{"server_name": {"web1"}}},
+		},
+		{
+			name: "valid cancellation",
+			request: Request{Method: "POST", Route: "/server/:server-number/cancellation", PathParams: server,
+				Form: url.Values{"cancellation_date": {"2030-01-31"}, "cancellation_reason": {"Upgrade to a new server"}, "reserve_location": {"false"}}},
+		},
+		{
+			name:        "missing required form parameter",
+			request:     Request{Method: "POST", Route: "/server/:server-number", PathParams: server},
+			wantMissing: []string{"server_name"},
+		},
+		{
+			name:        "empty required value",
+			request:     Request{Method: "POST", Route: "/server/:server-number", PathParams: server, Form: url.Values{"server_name": {""}}},
+			wantInvalid: []string{"server_name"},
+		},
+		{
+			name:        "unknown form parameter",
+			request:     Request{Method: "POST", Route: "/server/:server-number", PathParams: server, Form: url.Values{"server_name": {"web1"}, "colour": {"red"}}},
+			wantInvalid: []string{"colour"},
+		},
+		{
+			name:        "unknown query parameter",
+			request:     Request{Method: "GET", Route: "/server", Query: url.Values{"page": {"2"}}},
+			wantInvalid: []string{"page"},
+		},
+		{
+			name:        "path parameter of the wrong type",
+			request:     Request{Method: "GET", Route: "/server/:server-number", PathParams: map[string]string{"server-number": "abc"}},
+			wantInvalid: []string{"server-number"},
+		},
+		{
+			name:        "repeated scalar parameter",
+			request:     Request{Method: "POST", Route: "/server/:server-number", PathParams: server, Form: url.Values{"server_name": {"a", "b"}}},
+			wantInvalid: []string{"server_name"},
+		},
+		{
+			name: "bad date, enum and boolean",
+			request: Request{Method: "POST", Route: "/server/:server-number/cancellation", PathParams: server,
+				Form: url.Values{"cancellation_date": {"31.01.2030"}, "cancellation_reason": {"Bored"}, "reserve_location": {"yes"}}},
+			wantInvalid: []string{"cancellation_date", "cancellation_reason", "reserve_location"},
+		},
+		{
+			name:        "missing and invalid together",
+			request:     Request{Method: "POST", Route: "/server/:server-number", PathParams: map[string]string{"server-number": "x"}, Form: url.Values{"name": {"web1"}}},
+			wantMissing: []string{"server_name"},
+			wantInvalid: []string{"name", "server-number"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing, invalid, found := validator.Validate(tt.request)
+			if !found {
+				t.Fatal("operation not found")
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) || !reflect.DeepEqual(invalid, tt.wantInvalid) {
+				t.Errorf("missing %v, invalid %v, want %v and %v", missing, invalid, tt.wantMissing, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownOperation(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator: %v", err)
+	}
+	for _, request := range []Request{
+		{Method: "GET", Route: "/reset"},
+		{Method: "PUT", Route: "/server/:server-number"},
+	} {
+		if _, _, found := validator.Validate(request); found {
+			t.Errorf("%s %s found, want it skipped", request.Method, request.Route)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+
+	"hetzner-api-emulator/clock"
+	"hetzner-api-emulator/middlewares"
+	"hetzner-api-emulator/openapi"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Env зависимости, общие для Robot API и административного API
+type Env struct {
+	DB      *gorm.DB
+	DBType  string
+	Clock   *clock.Virtual
+	Faults  *middlewares.FaultInjector
+	Limits  *middlewares.RateLimiter
+	Journal *middlewares.Journal
+	Strict  *openapi.Validator // Проверка запросов по описанию OpenAPI; nil — строгий режим выключен
+
+	// TrustedProxies прокси, чьему X-Forwarded-For верит ClientIP; пусто — IP клиента берётся из соединения.
+	// От IP клиента зависят список разрешённых адресов пользователя и блокировка после неудачных входов
+	TrustedProxies []string
+
+	Audit     *middlewares.AuthAudit       // Журнал аудита аутентификации; nil — попытки не записываются
+	AuthCache *middlewares.CredentialCache // Кэш проверенных паролей; nil — bcrypt на каждый запрос
+	Lockout   *middlewares.LoginLockout    // Блокировка IP после неудачных входов
+}
+
+// NewRouter собирает роутер из групп: публичные маршруты без авторизации (/healthz, /readyz)
+// и маршруты Robot API за DBAuthMiddleware. Административный API подключают MountAdmin или NewAdminRouter
+func NewRouter(env *Env) *gin.Engine {
+	router := newEngine(env)
+
+	// Обработчик для несуществующих маршрутов
+	router.NoRoute(func(c *gin.Context) {
+		middlewares.RespondWithError(c, http.StatusNotFound, "ROUTE_NOT_FOUND", "Route not found")
+	})
+
+	// Подключаем обработчик ошибок
+	router.Use(middlewares.ErrorHandler())
+
+	// Публичные маршруты: проверки для оркестратора, без учётных данных и журнала
+	RegisterPublicRoutes(router.Group("/"), env.DB)
+
+	// Маршруты Robot: middleware авторизации, журнала запросов, областей доступа, ограничения запросов и внедрения сбоев (им нужен user_id)
+	authorized := router.Group("/",
+		middlewares.DBAuthMiddleware(env.DB, env.Audit, env.AuthCache, env.Lockout),
+		env.Journal.Middleware(),
+		middlewares.ScopeMiddleware(),
+		env.Limits.Middleware(),
+		env.Faults.Middleware(),
+	)
+
+	// В строгом режиме запросы проверяются по описанию OpenAPI до обработчиков
+	if env.Strict != nil {
+		authorized.Use(middlewares.StrictValidation(env.Strict))
+	}
+
+	// Регистрируем все маршруты через RegisterAllRoutes
+	RegisterAllRoutes(authorized, env.DB, env.DBType, env.Clock)
+
+	return router
+}
+
+// MountAdmin подключает административный API /__admin к роутеру
+func MountAdmin(router *gin.Engine, env *Env, token string) {
+	RegisterAdminRoutes(router.Group("/__admin", middlewares.AdminAuthMiddleware(token)), env)
+}
+
+// NewAdminRouter собирает отдельный роутер только с административным API
+func NewAdminRouter(env *Env, token string) *gin.Engine {
+	router := newEngine(env)
+	router.NoRoute(func(c *gin.Context) {
+		middlewares.RespondWithError(c, http.StatusNotFound, "ROUTE_NOT_FOUND", "Route not found")
+	})
+	MountAdmin(router, env, token)
+	return router
+}
+
+// newEngine создаёт gin.Engine с доверенными прокси из env. По умолчанию gin доверяет всем,
+// и любой клиент мог бы подставить свой адрес в X-Forwarded-For
+func newEngine(env *Env) *gin.Engine {
+	router := gin.Default()
+	if err := router.SetTrustedProxies(env.TrustedProxies); err != nil {
+		// Список проверяется при загрузке конфигурации; на неверный не полагаемся вовсе
+		log.Printf("Invalid trusted proxies %v, trusting none: %v", env.TrustedProxies, err)
+		router.SetTrustedProxies(nil)
+	}
+	return router
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEngineClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{"forwarded header ignored by default", nil, "192.0.2.10"},
+		{"forwarded header from an untrusted proxy", []string{"198.51.100.0/24"}, "192.0.2.10"},
+		{"forwarded header from a trusted proxy", []string{"192.0.2.10"}, "203.0.113.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newEngine(&Env{TrustedProxies: tt.proxies})
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = "192.0.2.10:40000"
+			req.Header.Set("X-Forwarded-For", "203.0.113.5")
+			req.Header.Set("X-Real-IP", "203.0.113.5")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if got := recorder.Body.String(); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
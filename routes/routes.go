@@ -1,28 +1,31 @@
 package routes
 
 import (
-	"hetzner-api-emulator/handlers"
+	"hetzner-api-emulator/clock"
+	adminHandlers "hetzner-api-emulator/handlers/admin"
+	healthHandlers "hetzner-api-emulator/handlers/health"
 	serverHandlers "hetzner-api-emulator/handlers/server"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterAllRoutes(router *gin.RouterGroup, db *gorm.DB, dbType string) {
+func RegisterAllRoutes(router *gin.RouterGroup, db *gorm.DB, dbType string, clk clock.Clock) {
 	router.Use(func(c *gin.Context) {
 		c.Set("db", db)
 		c.Set("dbType", dbType)
 		c.Next()
 	})
 
-	RegisterUserRoutes(router)
-	RegisterServerRoutes(router.Group("/server"), db, dbType)
+	RegisterServerRoutes(router.Group("/server"), db, clk)
 }
 
-func RegisterUserRoutes(router *gin.RouterGroup) {
-	router.POST("/register", handlers.RegisterUserHandler())
+// RegisterPublicRoutes регистрирует маршруты, доступные без авторизации
+func RegisterPublicRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	router.GET("/healthz", healthHandlers.Healthz(db))
+	router.GET("/readyz", healthHandlers.Readyz(db))
 }
 
-func RegisterServerRoutes(serverRouter *gin.RouterGroup, db *gorm.DB, dbType string) {
+func RegisterServerRoutes(serverRouter *gin.RouterGroup, db *gorm.DB, clk clock.Clock) {
 	// // Регистрация маршрута для получения списка серверов
 	serverRouter.GET("", serverHandlers.GetServers(db))
 	// // Маршрут для получения сервера по номеру
@@ -30,9 +33,67 @@ func RegisterServerRoutes(serverRouter *gin.RouterGroup, db *gorm.DB, dbType str
 	// // Маршрут для обновления имени сервера
 	serverRouter.POST("/:server-number", serverHandlers.UpdateServerName(db)) // Обновление имени
 	// // Маршрут для получения информации об отмене сервера
-	serverRouter.GET("/:server-number/cancellation", serverHandlers.GetServerCancellation(db)) // Отмена сервера
+	serverRouter.GET("/:server-number/cancellation", serverHandlers.GetServerCancellation(db, clk)) // Отмена сервера
 	// // Маршрут для получения информации об отмене сервера
-	// serverRouter.POST("/:server-number/cancellation", serverHandlers.PostServerCancellation(db, dbType)) // Отмена сервера
+	serverRouter.POST("/:server-number/cancellation", serverHandlers.PostServerCancellation(db, clk)) // Отмена сервера
 	// // Новый маршрут для отмены отмены
-	// serverRouter.DELETE("/:server-number/cancellation", serverHandlers.DeleteServerCancellation(db, dbType)) // Отмена отмены
+	serverRouter.DELETE("/:server-number/cancellation", serverHandlers.DeleteServerCancellation(db)) // Отмена отмены
+}
+
+// RegisterAdminRoutes регистрирует служебные маршруты для управления состоянием эмулятора (не часть Robot API)
+func RegisterAdminRoutes(router *gin.RouterGroup, env *Env) {
+	db := env.DB
+
+	router.GET("/state", adminHandlers.GetState(db))
+	router.GET("/openapi.json", adminHandlers.GetOpenAPI())
+
+	router.GET("/clock", adminHandlers.GetClock(env.Clock))
+	router.POST("/clock/freeze", adminHandlers.FreezeClock(env.Clock))
+	router.POST("/clock/unfreeze", adminHandlers.UnfreezeClock(env.Clock))
+	router.POST("/clock/set", adminHandlers.SetClock(env.Clock))
+	router.POST("/clock/advance", adminHandlers.AdvanceClock(env.Clock))
+	router.POST("/clock/reset", adminHandlers.ResetClock(env.Clock))
+
+	router.GET("/faults", adminHandlers.GetFaults(env.Faults))
+	router.PUT("/faults", adminHandlers.ReplaceFaults(env.Faults))
+	router.POST("/faults", adminHandlers.AddFault(env.Faults))
+	router.DELETE("/faults", adminHandlers.ClearFaults(env.Faults))
+
+	router.GET("/rate-limits", adminHandlers.GetRateLimits(env.Limits))
+	router.PUT("/rate-limits", adminHandlers.ReplaceRateLimits(env.Limits))
+	router.DELETE("/rate-limits/usage", adminHandlers.ResetRateLimitUsage(env.Limits))
+
+	router.GET("/requests", adminHandlers.ListRequests(env.Journal))
+	router.DELETE("/requests", adminHandlers.ClearRequests(env.Journal))
+
+	router.GET("/auth-events", adminHandlers.ListAuthEvents(db))
+	router.DELETE("/auth-events", adminHandlers.ClearAuthEvents(db))
+	router.GET("/auth-cache", adminHandlers.GetAuthCache(env.AuthCache))
+	router.DELETE("/auth-cache", adminHandlers.ClearAuthCache(env.AuthCache))
+
+	router.GET("/users", adminHandlers.ListUsers(db))
+	router.POST("/users", adminHandlers.CreateUser(db))
+	router.GET("/users/:id", adminHandlers.GetUser(db))
+	router.PUT("/users/:id", adminHandlers.UpdateUser(db))
+	router.DELETE("/users/:id", adminHandlers.DeleteUser(db))
+	router.POST("/users/:id/disable", adminHandlers.SetUserDisabled(db, true))
+	router.POST("/users/:id/enable", adminHandlers.SetUserDisabled(db, false))
+	router.POST("/users/:id/rotate-password", adminHandlers.RotateUserPassword(db))
+	router.PUT("/users/:id/scopes", adminHandlers.SetUserScopes(db))
+	router.PUT("/users/:id/allowed-ips", adminHandlers.SetUserAllowedIPs(db))
+
+	router.GET("/lockout", adminHandlers.GetLockout(env.Lockout))
+	router.PUT("/lockout", adminHandlers.ReplaceLockout(env.Lockout))
+	router.DELETE("/lockout/blocks", adminHandlers.ClearLockoutBlocks(env.Lockout))
+
+	router.GET("/servers", adminHandlers.ListServers(db))
+	router.POST("/servers", adminHandlers.CreateServer(db))
+	router.GET("/servers/:server-number", adminHandlers.GetServer(db))
+	router.PUT("/servers/:server-number", adminHandlers.UpdateServer(db))
+	router.DELETE("/servers/:server-number", adminHandlers.DeleteServer(db))
+
+	router.GET("/ips", adminHandlers.ListIPs(db))
+	router.POST("/ips", adminHandlers.CreateIP(db))
+	router.PUT("/ips/:id", adminHandlers.UpdateIP(db))
+	router.DELETE("/ips/:id", adminHandlers.DeleteIP(db))
 }